@@ -3,6 +3,10 @@ package healthcheck
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
 	"sync"
 	"time"
 )
@@ -14,55 +18,493 @@ type (
 	// A CheckOption is a modifier of a check. It can be passed while registering a checker to customize it.
 	CheckOption func(c *check)
 
+	// A Severity shows how much a failing check affects the overall status.
+	Severity int
+
 	// A checkerWithTimeout is a Checker function with timeout handler.
 	checkerWithTimeout func(ctx context.Context) error
 )
 
+// Severities
+const (
+	// Critical checks make the overall status unhealthy when they fail. It is the default severity.
+	Critical Severity = iota
+	// NonCritical checks only make the overall status degraded when they fail.
+	NonCritical
+)
+
+// maxDuration is the longest duration. Intervals stretched by backoff are capped at it.
+const maxDuration = time.Duration(math.MaxInt64)
+
+// defaultComponentType is the componentType of checks in application/health+json format if not set.
+const defaultComponentType = "component"
+
+// random is the random generator of jitters and staggers. It is seeded to spread checks of different processes.
+var (
+	random      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	randomMutex sync.Mutex
+)
+
 // Pre defined errors
 var (
-	// New Checkers have errNeverChecked error. It is useful for background checkers.
+	// New Checkers have errNeverChecked error and their status is unknown. It is useful for background checkers.
 	errNeverChecked = errors.New("this checker never checked")
+	// Checkers with unhealthy initial state have errAssumedUnhealthy error before their first execution.
+	errAssumedUnhealthy = errors.New("assumed unhealthy before the first check")
 	// A errTimeout returns when a Checker reach the timeout.
 	errTimeout = errors.New("timeout")
+	// A errStale returns when the result of a background check is too old, e.g. the scheduler is blocked.
+	errStale = errors.New("stale result")
+	// A errStillRunning returns when the previous execution of a Checker is still running after its timeout.
+	errStillRunning = errors.New("still running")
 )
 
+// A PanicError is the error of a Checker that panics.
+type PanicError struct {
+	// Value passed to panic. It is nil for panic(nil) or runtime.Goexit.
+	Value interface{}
+	// Stack trace of the goroutine when it panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// A hangTracker tracks the goroutine of a Checker, so a Checker that ignores its context
+// does not leave a new goroutine behind on every execution.
+type hangTracker struct {
+	mutex     sync.Mutex
+	running   bool
+	hung      bool
+	since     time.Time
+	abandoned uint64
+	// token identifies the last started execution.
+	token uint64
+}
+
+// start marks the start of an execution and returns its token.
+// It returns an error if the previous execution is still running after its timeout.
+func (t *hangTracker) start(now time.Time) (uint64, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.running && t.hung {
+		return 0, fmt.Errorf("%w since %v", errStillRunning, t.since.Format(time.RFC3339))
+	}
+	t.token++
+	t.running = true
+	t.hung = false
+	t.since = now
+	return t.token, nil
+}
+
+// abandon marks the running execution as hung, if it is still running.
+func (t *hangTracker) abandon() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.running && !t.hung {
+		t.hung = true
+		t.abandoned++
+	}
+}
+
+// finish marks the end of the execution with the token. It is ignored if a newer execution is started.
+func (t *hangTracker) finish(token uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if token != t.token {
+		return
+	}
+	t.running = false
+	t.hung = false
+}
+
+// abandonedCount returns the number of executions that were still running after their timeout.
+func (t *hangTracker) abandonedCount() uint64 {
+	if t == nil {
+		return 0
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.abandoned
+}
+
+// An unknownError is the error of a check with unknown initial state that fails under its threshold
+// and has never succeeded. It wraps the last error of the check.
+type unknownError struct {
+	err error
+}
+
+func (e *unknownError) Error() string {
+	return fmt.Sprintf("unknown, failing under threshold: %v", e.err)
+}
+
+func (e *unknownError) Unwrap() error {
+	return e.err
+}
+
+// A detachedContext carries the values of its parent, but not its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (d detachedContext) Value(key interface{}) interface{} { return d.parent.Value(key) }
+
 // A check holds data related to Checker and its results and other params.
 type check struct {
-	checker      checkerWithTimeout
-	timeout      time.Duration
-	interval     time.Duration
-	threshold    uint
-	err          error
-	errorsInARow uint
-	mutex        sync.RWMutex
+	checker           checkerWithTimeout
+	hang              *hangTracker
+	timeout           time.Duration
+	cacheTTL          time.Duration
+	staleAfter        time.Duration
+	staleIntervals    float64
+	interval          time.Duration
+	unhealthyInterval time.Duration
+	jitter            time.Duration
+	jitterRatio       float64
+	delay             time.Duration
+	stagger           bool
+	backoffFactor     float64
+	backoffMax        time.Duration
+	threshold         uint
+	initial           Status
+	grace             time.Duration
+	created           time.Time
+	firstRun          time.Time
+	notified          Status
+	recovery          uint
+	window            *failureWindow
+	severity          Severity
+	component         string
+	tags              []string
+	err               error
+	lastErr           error
+	failed            bool
+	revalidating      bool
+	inflight          chan struct{}
+	errorsInARow      uint
+	successesInARow   uint
+	runs              uint64
+	failures          uint64
+	panics            uint64
+	lastRun           time.Time
+	lastSuccess       time.Time
+	lastFailure       time.Time
+	lastDuration      time.Duration
+	onStatusChange    func(old, new Status, err error, t time.Time)
+	mutex             sync.RWMutex
+}
+
+// A result is a snapshot of results of a check.
+type result struct {
+	err             error
+	errorsInARow    uint
+	successesInARow uint
+	runs            uint64
+	failures        uint64
+	panics          uint64
+	abandoned       uint64
+	lastRun         time.Time
+	lastSuccess     time.Time
+	lastFailure     time.Time
+	lastDuration    time.Duration
+	failureRatio    *float64
+	interval        time.Duration
+}
+
+// A failureWindow is a sliding window of the last results of a check.
+type failureWindow struct {
+	failed   []bool
+	next     int
+	count    int
+	failures int
+	maxRatio float64
+}
+
+// add records a result in the window and drops the oldest one if the window is full.
+func (w *failureWindow) add(failed bool) {
+	if w.count == len(w.failed) {
+		if w.failed[w.next] {
+			w.failures--
+		}
+	} else {
+		w.count++
+	}
+	w.failed[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.failed)
+}
+
+// ratio returns the ratio of failures in the window.
+func (w *failureWindow) ratio() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return float64(w.failures) / float64(w.count)
+}
+
+// exceeded shows if the window is full and the ratio of failures in it exceeds the max ratio.
+func (w *failureWindow) exceeded() bool {
+	return w.count == len(w.failed) && w.ratio() > w.maxRatio
 }
 
 // check checks the healthiness of a service.
-// If the check is not a background check, it runs the Checker.
+// If the check is not a background check, it runs the Checker, unless the last result is still cached.
+// With stale-while-revalidate, it refreshes a stale result asynchronously and does not wait for it.
 // It checks the threshold of the errors and return err value if threshold passes. err value can be nil.
 func (c *check) check(ctx context.Context) error {
 	if c.interval == 0 {
-		c.run(ctx)
+		now := time.Now()
+		switch {
+		case c.fresh(now, c.cacheTTL):
+		case c.staleAfter > 0 && c.result().runs > 0:
+			if !c.fresh(now, c.staleAfter) {
+				c.revalidate()
+			}
+		default:
+			c.run(ctx)
+		}
+	}
+	return c.state()
+}
+
+// fresh shows if the last result is younger than ttl at now.
+func (c *check) fresh(now time.Time, ttl time.Duration) bool {
+	if ttl <= 0 {
+		return false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return !c.lastRun.IsZero() && now.Sub(c.lastRun) < ttl
+}
+
+// revalidate runs the Checker in a goroutine, if it is not already revalidating.
+// It does not use the context of the caller, so the refresh is not canceled when the caller is gone.
+func (c *check) revalidate() {
+	c.mutex.Lock()
+	if c.revalidating {
+		c.mutex.Unlock()
+		return
 	}
+	c.revalidating = true
+	c.mutex.Unlock()
+	go func() {
+		c.run(context.Background())
+		c.mutex.Lock()
+		c.revalidating = false
+		c.mutex.Unlock()
+	}()
+}
+
+// state returns the threshold adjusted error of the last execution without running the Checker.
+// A healthy background check returns a stale error if its result is too old.
+func (c *check) state() error {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	if c.errorsInARow < c.threshold {
+	return c.stateErr(time.Now())
+}
+
+// stateErr returns the threshold adjusted error of the last execution, or a stale error at now.
+// c.mutex should be locked.
+func (c *check) stateErr(now time.Time) error {
+	if err := c.thresholdErr(); err != nil {
+		return err
+	}
+	return c.staleErr(now)
+}
+
+// staleErr returns an error if the last result of a background check is older than staleIntervals intervals
+// plus the timeout at now. A check that never ran is as old as the time of its first scheduled execution.
+// c.mutex should be locked.
+func (c *check) staleErr(now time.Time) error {
+	if c.staleIntervals <= 0 || c.interval == 0 {
+		return nil
+	}
+	maxAge := addDurations(scaleDuration(c.effectiveInterval(), c.staleIntervals), c.timeout)
+	if c.lastRun.IsZero() {
+		if age := now.Sub(c.firstRun); !c.firstRun.IsZero() && age > maxAge {
+			return fmt.Errorf("%w: never run in %v", errStale, age.Round(time.Millisecond))
+		}
+		return nil
+	}
+	if age := now.Sub(c.lastRun); age > maxAge {
+		return fmt.Errorf("%w: last run %v ago", errStale, age.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// thresholdErr returns the threshold adjusted error of the last execution. c.mutex should be locked.
+// A failed check stays unhealthy with its last error until it passes the recovery threshold.
+func (c *check) thresholdErr() error {
+	if c.err == nil {
+		if c.failed {
+			return c.lastErr
+		}
+		return nil
+	}
+	if c.errorsInARow < c.threshold && !c.failed {
+		if c.initial == StatusUnknown && c.lastSuccess.IsZero() {
+			if c.runs == 0 {
+				return errNeverChecked
+			}
+			return &unknownError{c.err}
+		}
 		return nil
 	}
 	return c.err
 }
 
-// run executes a Checker.
+// run executes a Checker and records its result. The Checker runs without locking the check,
+// so the last result is available while it is running.
+// Concurrent callers share a single execution: they wait for the running one instead of starting another,
+// so the threshold counts executions rather than callers.
+// The execution is detached from the cancellation of ctx, so a caller that gives up, e.g. a disconnected prober,
+// stops waiting but does not fail the check. It is only limited by the timeout of the check.
+// Only the caller that starts the execution waits for onStatusChange, the others wait for the result.
 func (c *check) run(ctx context.Context) {
+	c.mutex.Lock()
+	wait := c.inflight
+	if wait == nil {
+		inflight := make(chan struct{})
+		notified := make(chan struct{})
+		c.inflight = inflight
+		wait = notified
+		go c.execute(detachedContext{ctx}, inflight, notified)
+	}
+	c.mutex.Unlock()
+	select {
+	case <-wait:
+	case <-ctx.Done():
+	}
+}
+
+// execute executes a Checker, records its result and closes inflight when it is done.
+// Then it calls onStatusChange if the threshold adjusted status of the check changes and closes notified,
+// so waiters don't wait for listeners and listeners can run the check again.
+func (c *check) execute(ctx context.Context, inflight, notified chan struct{}) {
+	defer close(notified)
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
+	start := time.Now()
+	err := c.checker(ctx)
+	duration := time.Since(start)
+	c.mutex.Lock()
+	if c.notified == "" {
+		c.notified = errStatus(c.stateErr(start))
+	}
+	c.record(err, start, duration)
+	old, new, newErr, changed := c.transition(time.Now())
+	c.inflight = nil
+	close(inflight)
+	c.mutex.Unlock()
+	if changed && c.onStatusChange != nil {
+		c.onStatusChange(old, new, newErr, start)
+	}
+}
+
+// refresh calls onStatusChange if the status of the check changed without an execution at now,
+// e.g. its result got stale.
+func (c *check) refresh(now time.Time) {
+	c.mutex.Lock()
+	old, new, err, changed := c.transition(now)
+	c.mutex.Unlock()
+	if changed && c.onStatusChange != nil {
+		c.onStatusChange(old, new, err, now)
+	}
+}
+
+// transition updates the last notified status of the check with its status at now,
+// and shows if it changed. c.mutex should be locked.
+func (c *check) transition(now time.Time) (old, new Status, err error, changed bool) {
+	err = c.stateErr(now)
+	old, new = c.notified, errStatus(err)
+	c.notified = new
+	return old, new, err, old != "" && old != new
+}
+
+// schedule sets the time of the first scheduled execution of a background check, if it is not set yet.
+func (c *check) schedule(first time.Time) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	c.err = c.checker(ctx)
+	if c.firstRun.IsZero() {
+		c.firstRun = first
+	}
+}
+
+// record records the result of an execution of the Checker. c.mutex should be locked.
+func (c *check) record(err error, start time.Time, duration time.Duration) {
+	c.err = err
+	c.lastRun = start
+	c.lastDuration = duration
+	c.runs++
+	var ratioExceeded bool
+	if c.window != nil {
+		c.window.add(c.err != nil)
+		ratioExceeded = c.window.exceeded()
+	}
 	if c.err != nil {
 		c.errorsInARow++
+		c.successesInARow = 0
+		c.failures++
+		c.lastFailure = start
+		var panicErr *PanicError
+		if errors.As(c.err, &panicErr) {
+			c.panics++
+		}
+		c.lastErr = c.err
+		c.failed = c.failed || c.errorsInARow >= c.threshold || ratioExceeded
 	} else {
 		c.errorsInARow = 0
+		c.successesInARow++
+		c.lastSuccess = start
+		c.failed = ratioExceeded || (c.failed && c.successesInARow < c.recovery)
+	}
+}
+
+// result returns a snapshot of results of a check.
+func (c *check) result() result {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	var failureRatio *float64
+	if c.window != nil {
+		ratio := c.window.ratio()
+		failureRatio = &ratio
+	}
+	return result{
+		err:             c.err,
+		errorsInARow:    c.errorsInARow,
+		successesInARow: c.successesInARow,
+		runs:            c.runs,
+		failures:        c.failures,
+		panics:          c.panics,
+		abandoned:       c.hang.abandonedCount(),
+		lastRun:         c.lastRun,
+		lastSuccess:     c.lastSuccess,
+		lastFailure:     c.lastFailure,
+		lastDuration:    c.lastDuration,
+		failureRatio:    failureRatio,
+		interval:        c.effectiveInterval(),
+	}
+}
+
+// errStatus converts a threshold adjusted error to the Status of a check.
+func errStatus(err error) Status {
+	var unknownErr *unknownError
+	switch {
+	case err == nil:
+		return StatusHealthy
+	case errors.Is(err, errNeverChecked), errors.As(err, &unknownErr):
+		return StatusUnknown
+	default:
+		return StatusUnhealthy
 	}
 }
 
@@ -71,9 +513,122 @@ func (c *check) isInBackground() bool {
 	return c.interval != 0
 }
 
-// ticker creates a ticker for a check.
-func (c *check) ticker() *time.Ticker {
-	return time.NewTicker(c.interval)
+// isCritical shows if a failing check makes the overall status unhealthy.
+func (c *check) isCritical() bool {
+	return c.severity == Critical
+}
+
+// componentType returns type of the component that a check checks, e.g. datastore.
+func (c *check) componentType() string {
+	if c.component == "" {
+		return defaultComponentType
+	}
+	return c.component
+}
+
+// hasTag shows if a check carries any of the tags. Every check matches an empty tags list.
+func (c *check) hasTag(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	for i := range tags {
+		for j := range c.tags {
+			if tags[i] == c.tags[j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextInterval returns the duration between the scheduled times of two background executions, with jitter.
+func (c *check) nextInterval() time.Duration {
+	c.mutex.RLock()
+	interval := c.effectiveInterval()
+	c.mutex.RUnlock()
+	return addJitter(interval, addDurations(c.jitter, scaleDuration(interval, c.jitterRatio)))
+}
+
+// effectiveInterval returns the interval of a background check, stretched by backoff while it keeps failing.
+// Unhealthy checks use the unhealthy interval if it is set. c.mutex should be locked.
+func (c *check) effectiveInterval() time.Duration {
+	base := c.interval
+	if c.unhealthyInterval > 0 && c.thresholdErr() != nil {
+		base = c.unhealthyInterval
+	}
+	if c.backoffFactor <= 1 || c.errorsInARow == 0 {
+		return base
+	}
+	interval := scaleDuration(base, math.Pow(c.backoffFactor, float64(c.errorsInARow)))
+	if c.backoffMax > 0 && interval >= c.backoffMax {
+		return c.backoffMax
+	}
+	return interval
+}
+
+// inGracePeriod shows if a check is in its grace period at now, i.e. it is younger than its grace period
+// and has not succeeded yet.
+func (c *check) inGracePeriod(now time.Time) bool {
+	if c.grace <= 0 {
+		return false
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return now.Sub(c.created) < c.grace && c.lastSuccess.IsZero()
+}
+
+// initialDelay returns the duration before the first background execution, with stagger.
+func (c *check) initialDelay() time.Duration {
+	if c.stagger {
+		return c.delay + randomDuration(c.interval)
+	}
+	return c.delay
+}
+
+// addJitter adds a random duration in [-jitter, jitter) to an interval. The result is never negative.
+func addJitter(interval, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	if jitter > maxDuration/2 {
+		jitter = maxDuration / 2
+	}
+	offset := randomDuration(2*jitter) - jitter
+	if offset > 0 {
+		return addDurations(interval, offset)
+	}
+	interval += offset
+	if interval < 0 {
+		return 0
+	}
+	return interval
+}
+
+// scaleDuration multiplies a non-negative duration by a non-negative factor, capped at maxDuration.
+func scaleDuration(d time.Duration, factor float64) time.Duration {
+	scaled := float64(d) * factor
+	if scaled >= float64(maxDuration) {
+		return maxDuration
+	}
+	return time.Duration(scaled)
+}
+
+// addDurations adds two non-negative durations, capped at maxDuration.
+func addDurations(a, b time.Duration) time.Duration {
+	if a > maxDuration-b {
+		return maxDuration
+	}
+	return a + b
+}
+
+// randomDuration returns a random duration in [0, max), or 0 if max is not positive.
+func randomDuration(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
+	return time.Duration(random.Int63n(int64(max)))
 }
 
 // newCheck creates a new instance of check.
@@ -81,10 +636,14 @@ func (c *check) ticker() *time.Ticker {
 // 	timeout	The timeout of a check when executing
 //	ops		Check Options e.g. InBackground
 func newCheck(c Checker, timeout time.Duration, opts ...CheckOption) *check {
+	hang := &hangTracker{}
 	s := check{
-		checker: newCheckerWithTimeout(c, timeout),
+		checker: newCheckerWithTimeout(c, timeout, hang),
+		hang:    hang,
 		timeout: timeout,
 		err:     errNeverChecked,
+		initial: StatusUnknown,
+		created: time.Now(),
 	}
 	for i := range opts {
 		opts[i](&s)
@@ -92,19 +651,40 @@ func newCheck(c Checker, timeout time.Duration, opts ...CheckOption) *check {
 	return &s
 }
 
-// newCheckerWithTimeout creates a Checker with a timeout
-func newCheckerWithTimeout(c Checker, timeout time.Duration) checkerWithTimeout {
+// newCheckerWithTimeout creates a Checker with a timeout.
+// A panic of the Checker is recovered and returned as a PanicError.
+// A Checker that is still running after the timeout is abandoned, and no new execution starts until it returns.
+func newCheckerWithTimeout(c Checker, timeout time.Duration, hang *hangTracker) checkerWithTimeout {
 	return func(ctx context.Context) error {
+		token, err := hang.start(time.Now())
+		if err != nil {
+			return err
+		}
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 		errChan := make(chan error, 1)
+		// The tracker is finished before sending the result, so it is never finished after the next start.
+		// A Checker that does not return normally, even by panic(nil) or runtime.Goexit, is reported as a PanicError.
 		go func() {
-			errChan <- c(ctx)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				v := recover()
+				hang.finish(token)
+				errChan <- &PanicError{Value: v, Stack: debug.Stack()}
+			}()
+			err := c(ctx)
+			completed = true
+			hang.finish(token)
+			errChan <- err
 		}()
 		select {
 		case err := <-errChan:
 			return err
 		case <-ctx.Done():
+			hang.abandon()
 			return errTimeout
 		}
 	}
@@ -118,11 +698,178 @@ func InBackground(interval time.Duration) CheckOption {
 	}
 }
 
+// WithJitter adds a random duration in [-jitter, jitter) to each interval of a background check.
+// It spreads executions of checks of different processes on a shared dependency.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithJitter(jitter time.Duration) CheckOption {
+	return func(c *check) {
+		c.jitter = jitter
+	}
+}
+
+// WithJitterRatio is WithJitter with a jitter relative to the interval, e.g. 0.1 for ±10% of the interval.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithJitterRatio(ratio float64) CheckOption {
+	return func(c *check) {
+		c.jitterRatio = ratio
+	}
+}
+
+// WithInitialDelay delays the first execution of a background check.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithInitialDelay(delay time.Duration) CheckOption {
+	return func(c *check) {
+		c.delay = delay
+	}
+}
+
+// WithStaggeredStart delays the first execution of a background check by a random duration in [0, interval),
+// in addition to WithInitialDelay. It spreads background checks over the interval after start.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithStaggeredStart() CheckOption {
+	return func(c *check) {
+		c.stagger = true
+	}
+}
+
+// WithCacheTTL reuses the last result of a synchronous check if it is younger than ttl, instead of running the Checker.
+// It protects services with expensive checks from frequent health check requests.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithCacheTTL(ttl time.Duration) CheckOption {
+	return func(c *check) {
+		c.cacheTTL = ttl
+	}
+}
+
+// WithStaleWhileRevalidate makes a synchronous check return its last result immediately.
+// If the result is older than staleAfter, the Checker runs asynchronously to refresh it.
+// Only the first execution of the check is synchronous. The check never runs if nobody asks for it.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithStaleWhileRevalidate(staleAfter time.Duration) CheckOption {
+	return func(c *check) {
+		c.staleAfter = staleAfter
+	}
+}
+
+// WithStaleResult makes a background check unhealthy if its last result is older than intervals times its interval,
+// plus its timeout, e.g. when the background goroutine is blocked. A check that never runs gets stale too,
+// counting from its first scheduled execution. Listeners of status changes are notified when a result gets stale.
+// By default, results never get stale.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithStaleResult(intervals float64) CheckOption {
+	return func(c *check) {
+		c.staleIntervals = intervals
+	}
+}
+
+// WithCheckGracePeriod ignores failures of a check in the overall status for a period after its registration,
+// until it succeeds once. Failures are still reported in the detail.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithCheckGracePeriod(period time.Duration) CheckOption {
+	return func(c *check) {
+		c.grace = period
+	}
+}
+
+// WithIntervals forces a check to run in the background, with different intervals for healthy and unhealthy states.
+// E.g. run slowly while healthy and faster while unhealthy or recovering, to detect recovery quickly.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithIntervals(healthy, unhealthy time.Duration) CheckOption {
+	return func(c *check) {
+		c.interval = healthy
+		c.unhealthyInterval = unhealthy
+	}
+}
+
+// WithBackoff stretches the interval of a background check while it keeps failing.
+// The interval is multiplied by factor per error in a row, up to max. It resets on success.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithBackoff(factor float64, max time.Duration) CheckOption {
+	return func(c *check) {
+		c.backoffFactor = factor
+		c.backoffMax = max
+	}
+}
+
 // WithThreshold adds a threshold of errors in the row to show unhealthy state.
+// It does not change the initial state of the check, see WithInitialState.
 // Returns a CheckOption that can be passed during the Checker registration.
 func WithThreshold(threshold uint) CheckOption {
 	return func(c *check) {
 		c.threshold = threshold
-		c.errorsInARow = threshold
+	}
+}
+
+// WithInitialState sets the status of a check before its first execution. Default is StatusUnknown.
+// 	StatusHealthy	assumes the check passes until it fails.
+// 	StatusUnhealthy	assumes the check fails until it passes.
+// 	StatusUnknown	the check is unknown until it passes, or fails more than its threshold.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithInitialState(status Status) CheckOption {
+	return func(c *check) {
+		c.initial = status
+		c.failed = false
+		c.lastErr = nil
+		switch status {
+		case StatusHealthy:
+			c.err = nil
+		case StatusUnhealthy:
+			c.err = errAssumedUnhealthy
+			c.lastErr = errAssumedUnhealthy
+			c.failed = true
+		default:
+			c.initial = StatusUnknown
+			c.err = errNeverChecked
+		}
+	}
+}
+
+// WithComponentType sets type of the component that a check checks, e.g. datastore or system.
+// It is the componentType of the check in application/health+json format. Default is component.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithComponentType(componentType string) CheckOption {
+	return func(c *check) {
+		c.component = componentType
+	}
+}
+
+// WithTags adds tags to a check. Handlers registered by HealthCheck.Handle only evaluate checks carrying their tags.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithTags(tags ...string) CheckOption {
+	return func(c *check) {
+		c.tags = append(c.tags, tags...)
+	}
+}
+
+// WithFailureRatio keeps a window of the last results of a check and shows unhealthy state
+// when the window is full and the ratio of failures in it exceeds maxRatio, e.g. 0.5 for 50%.
+// It is in addition to WithThreshold, raise the threshold to only rely on the failure ratio.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithFailureRatio(window uint, maxRatio float64) CheckOption {
+	return func(c *check) {
+		if window == 0 {
+			c.window = nil
+			return
+		}
+		c.window = &failureWindow{
+			failed:   make([]bool, window),
+			maxRatio: maxRatio,
+		}
+	}
+}
+
+// WithSeverity sets the severity of a check. A failing NonCritical check only makes the overall status degraded.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithSeverity(severity Severity) CheckOption {
+	return func(c *check) {
+		c.severity = severity
+	}
+}
+
+// WithRecoveryThreshold adds a threshold of successes in the row to show healthy state after an unhealthy state.
+// Returns a CheckOption that can be passed during the Checker registration.
+func WithRecoveryThreshold(threshold uint) CheckOption {
+	return func(c *check) {
+		c.recovery = threshold
 	}
 }
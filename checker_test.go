@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -15,20 +18,20 @@ func TestInBackground(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		c    check
+		c    *check
 	}{
 		{
 			"in_background",
 			args{
 				time.Minute,
 			},
-			check{},
+			&check{},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			opt := InBackground(tt.args.interval)
-			opt(&tt.c)
+			opt(tt.c)
 			if tt.c.interval != tt.args.interval {
 				t.Errorf("InBackground().interval = %v, want %v", tt.c.interval, tt.args.interval)
 			}
@@ -36,6 +39,109 @@ func TestInBackground(t *testing.T) {
 	}
 }
 
+func TestWithJitter(t *testing.T) {
+	c := &check{}
+	WithJitter(time.Second)(c)
+	WithJitterRatio(0.1)(c)
+	if c.jitter != time.Second {
+		t.Errorf("WithJitter().jitter = %v, want %v", c.jitter, time.Second)
+	}
+	if c.jitterRatio != 0.1 {
+		t.Errorf("WithJitterRatio().jitterRatio = %v, want %v", c.jitterRatio, 0.1)
+	}
+}
+
+func TestWithInitialDelay(t *testing.T) {
+	c := &check{}
+	WithInitialDelay(time.Second)(c)
+	WithStaggeredStart()(c)
+	if c.delay != time.Second {
+		t.Errorf("WithInitialDelay().delay = %v, want %v", c.delay, time.Second)
+	}
+	if !c.stagger {
+		t.Error("WithStaggeredStart().stagger = false, want true")
+	}
+}
+
+func TestWithCacheTTL(t *testing.T) {
+	c := &check{}
+	WithCacheTTL(time.Second)(c)
+	if c.cacheTTL != time.Second {
+		t.Errorf("WithCacheTTL().cacheTTL = %v, want %v", c.cacheTTL, time.Second)
+	}
+}
+
+func TestWithStaleWhileRevalidate(t *testing.T) {
+	c := &check{}
+	WithStaleWhileRevalidate(time.Second)(c)
+	if c.staleAfter != time.Second {
+		t.Errorf("WithStaleWhileRevalidate().staleAfter = %v, want %v", c.staleAfter, time.Second)
+	}
+}
+
+func TestWithStaleResult(t *testing.T) {
+	c := &check{}
+	WithStaleResult(3)(c)
+	if c.staleIntervals != 3 {
+		t.Errorf("WithStaleResult().staleIntervals = %v, want %v", c.staleIntervals, 3)
+	}
+}
+
+func TestWithCheckGracePeriod(t *testing.T) {
+	c := &check{}
+	WithCheckGracePeriod(time.Minute)(c)
+	if c.grace != time.Minute {
+		t.Errorf("WithCheckGracePeriod().grace = %v, want %v", c.grace, time.Minute)
+	}
+}
+
+func Test_check_inGracePeriod(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name        string
+		grace       time.Duration
+		created     time.Time
+		lastSuccess time.Time
+		want        bool
+	}{
+		{"disabled", 0, now, time.Time{}, false},
+		{"in_grace_period", time.Minute, now.Add(-time.Second), time.Time{}, true},
+		{"expired", time.Minute, now.Add(-time.Minute), time.Time{}, false},
+		{"succeeded", time.Minute, now.Add(-time.Second), now, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				grace:       tt.grace,
+				created:     tt.created,
+				lastSuccess: tt.lastSuccess,
+			}
+			if got := c.inGracePeriod(now); got != tt.want {
+				t.Errorf("inGracePeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithIntervals(t *testing.T) {
+	c := &check{}
+	WithIntervals(time.Minute, time.Second)(c)
+	if c.interval != time.Minute || c.unhealthyInterval != time.Second {
+		t.Errorf("WithIntervals() = %v, %v, want %v, %v", c.interval, c.unhealthyInterval, time.Minute, time.Second)
+	}
+	if !c.isInBackground() {
+		t.Error("WithIntervals() isInBackground = false, want true")
+	}
+}
+
+func TestWithBackoff(t *testing.T) {
+	c := &check{}
+	WithBackoff(2, time.Minute)(c)
+	if c.backoffFactor != 2 || c.backoffMax != time.Minute {
+		t.Errorf("WithBackoff() = %v, %v, want %v, %v", c.backoffFactor, c.backoffMax, 2, time.Minute)
+	}
+}
+
 func TestWithThreshold(t *testing.T) {
 	type args struct {
 		threshold uint
@@ -43,19 +149,19 @@ func TestWithThreshold(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		c    check
+		c    *check
 	}{
 		{
 			"in_background",
 			args{
 				5,
 			},
-			check{},
+			&check{},
 		}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			opt := WithThreshold(tt.args.threshold)
-			opt(&tt.c)
+			opt(tt.c)
 			if tt.c.threshold != tt.args.threshold {
 				t.Errorf("InBackground().threshold = %v, want %v", tt.c.threshold, tt.args.threshold)
 			}
@@ -63,6 +169,209 @@ func TestWithThreshold(t *testing.T) {
 	}
 }
 
+func TestWithRecoveryThreshold(t *testing.T) {
+	c := &check{}
+	opt := WithRecoveryThreshold(3)
+	opt(c)
+	if c.recovery != 3 {
+		t.Errorf("WithRecoveryThreshold().recovery = %v, want %v", c.recovery, 3)
+	}
+}
+
+func TestWithFailureRatio(t *testing.T) {
+	type args struct {
+		window   uint
+		maxRatio float64
+	}
+	tests := []struct {
+		name string
+		args args
+		want *failureWindow
+	}{
+		{
+			"disabled",
+			args{0, 0.5},
+			nil,
+		},
+		{
+			"window",
+			args{10, 0.5},
+			&failureWindow{failed: make([]bool, 10), maxRatio: 0.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{}
+			opt := WithFailureRatio(tt.args.window, tt.args.maxRatio)
+			opt(c)
+			if !reflect.DeepEqual(c.window, tt.want) {
+				t.Errorf("WithFailureRatio().window = %v, want %v", c.window, tt.want)
+			}
+		})
+	}
+}
+
+func Test_failureWindow(t *testing.T) {
+	tests := []struct {
+		name         string
+		size         int
+		results      []bool
+		wantRatio    float64
+		wantExceeded bool
+	}{
+		{
+			"empty",
+			3,
+			nil,
+			0,
+			false,
+		},
+		{
+			"not_full",
+			3,
+			[]bool{true, true},
+			1,
+			false,
+		},
+		{
+			"full_exceeded",
+			3,
+			[]bool{true, false, true},
+			2.0 / 3,
+			true,
+		},
+		{
+			"full_not_exceeded",
+			4,
+			[]bool{true, false, true, false},
+			0.5,
+			false,
+		},
+		{
+			"slide",
+			3,
+			[]bool{true, true, true, false, false},
+			1.0 / 3,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &failureWindow{failed: make([]bool, tt.size), maxRatio: 0.5}
+			for _, failed := range tt.results {
+				w.add(failed)
+			}
+			if got := w.ratio(); got != tt.wantRatio {
+				t.Errorf("failureWindow.ratio() = %v, want %v", got, tt.wantRatio)
+			}
+			if got := w.exceeded(); got != tt.wantExceeded {
+				t.Errorf("failureWindow.exceeded() = %v, want %v", got, tt.wantExceeded)
+			}
+		})
+	}
+}
+
+func TestWithSeverity(t *testing.T) {
+	tests := []struct {
+		name     string
+		severity Severity
+		want     bool
+	}{
+		{
+			"critical",
+			Critical,
+			true,
+		},
+		{
+			"non_critical",
+			NonCritical,
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{}
+			opt := WithSeverity(tt.severity)
+			opt(c)
+			if c.severity != tt.severity {
+				t.Errorf("WithSeverity().severity = %v, want %v", c.severity, tt.severity)
+			}
+			if got := c.isCritical(); got != tt.want {
+				t.Errorf("isCritical() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithComponentType(t *testing.T) {
+	tests := []struct {
+		name          string
+		componentType string
+		want          string
+	}{
+		{
+			"default",
+			"",
+			"component",
+		},
+		{
+			"datastore",
+			"datastore",
+			"datastore",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{}
+			opt := WithComponentType(tt.componentType)
+			opt(c)
+			if got := c.componentType(); got != tt.want {
+				t.Errorf("componentType() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTags(t *testing.T) {
+	type args struct {
+		tags []string
+	}
+	tests := []struct {
+		name string
+		args args
+		c    *check
+		want []string
+	}{
+		{
+			"empty",
+			args{},
+			&check{},
+			nil,
+		},
+		{
+			"2_tags",
+			args{[]string{"liveness", "readiness"}},
+			&check{},
+			[]string{"liveness", "readiness"},
+		},
+		{
+			"append",
+			args{[]string{"readiness"}},
+			&check{tags: []string{"liveness"}},
+			[]string{"liveness", "readiness"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := WithTags(tt.args.tags...)
+			opt(tt.c)
+			if !reflect.DeepEqual(tt.c.tags, tt.want) {
+				t.Errorf("WithTags().tags = %v, want %v", tt.c.tags, tt.want)
+			}
+		})
+	}
+}
+
 func Test_check_check(t *testing.T) {
 	testErr := errors.New("check.check error")
 	checkerCreator := func(err error) checkerWithTimeout {
@@ -72,223 +381,1095 @@ func Test_check_check(t *testing.T) {
 	}
 
 	type fields struct {
-		checker     checkerWithTimeout
-		timeout     time.Duration
-		interval    time.Duration
-		threshold   uint
-		err         error
-		errorsOnRow uint
+		checker     checkerWithTimeout
+		timeout     time.Duration
+		interval    time.Duration
+		cacheTTL    time.Duration
+		threshold   uint
+		err         error
+		errorsOnRow uint
+		lastRun     time.Time
+	}
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   error
+	}{
+		{
+			"simple",
+			fields{
+				checker: checkerCreator(testErr),
+				err:     nil,
+			},
+			args{context.Background()},
+			testErr,
+		},
+		{
+			"with_threshold_not_passed",
+			fields{
+				checker:     checkerCreator(testErr),
+				err:         testErr,
+				threshold:   3,
+				errorsOnRow: 1,
+			},
+			args{context.Background()},
+			nil,
+		},
+		{
+			"with_threshold_passed",
+			fields{
+				checker:     checkerCreator(testErr),
+				err:         testErr,
+				threshold:   2,
+				errorsOnRow: 1,
+			},
+			args{context.Background()},
+			testErr,
+		},
+		{
+			"in_background",
+			fields{
+				checker:  checkerCreator(nil),
+				err:      testErr,
+				interval: time.Second,
+			},
+			args{context.Background()},
+			testErr,
+		},
+		{
+			"cached",
+			fields{
+				checker:  checkerCreator(nil),
+				err:      testErr,
+				cacheTTL: time.Minute,
+				lastRun:  time.Now(),
+			},
+			args{context.Background()},
+			testErr,
+		},
+		{
+			"cache_expired",
+			fields{
+				checker:  checkerCreator(nil),
+				err:      testErr,
+				cacheTTL: time.Minute,
+				lastRun:  time.Now().Add(-time.Hour),
+			},
+			args{context.Background()},
+			nil,
+		},
+		{
+			"in_background_with_threshold_not_passed",
+			fields{
+				checker:   checkerCreator(testErr),
+				err:       testErr,
+				threshold: 1,
+				interval:  time.Second,
+			},
+			args{context.Background()},
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				checker:      tt.fields.checker,
+				timeout:      tt.fields.timeout,
+				interval:     tt.fields.interval,
+				cacheTTL:     tt.fields.cacheTTL,
+				threshold:    tt.fields.threshold,
+				err:          tt.fields.err,
+				errorsInARow: tt.fields.errorsOnRow,
+				lastRun:      tt.fields.lastRun,
+			}
+			if got := c.check(tt.args.ctx); got != tt.want {
+				t.Errorf("check() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_check_fresh(t *testing.T) {
+	now := time.Now()
+	type args struct {
+		lastRun time.Time
+		ttl     time.Duration
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			"without_ttl",
+			args{lastRun: now},
+			false,
+		},
+		{
+			"never_run",
+			args{ttl: time.Minute},
+			false,
+		},
+		{
+			"fresh",
+			args{lastRun: now.Add(-time.Second), ttl: time.Minute},
+			true,
+		},
+		{
+			"expired",
+			args{lastRun: now.Add(-time.Minute), ttl: time.Minute},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				lastRun: tt.args.lastRun,
+			}
+			if got := c.fresh(now, tt.args.ttl); got != tt.want {
+				t.Errorf("fresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A caller that gives up does not fail the check.
+func Test_check_run_canceled(t *testing.T) {
+	release := make(chan struct{})
+	c := newCheck(func(ctx context.Context) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.run(ctx)
+	close(release)
+	c.run(context.Background())
+	r := c.result()
+	if r.err != nil || r.errorsInARow != 0 || r.failures != 0 {
+		t.Errorf("check.run() err, errorsInARow, failures = %v, %v, %v, want nil, 0, 0", r.err, r.errorsInARow, r.failures)
+	}
+}
+
+func Test_detachedContext(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithTimeout(context.WithValue(context.Background(), key{}, "value"), time.Millisecond)
+	cancel()
+	ctx := detachedContext{parent}
+	if ctx.Err() != nil {
+		t.Errorf("detachedContext.Err() = %v, want nil", ctx.Err())
+	}
+	if ctx.Done() != nil {
+		t.Error("detachedContext.Done() is not nil")
+	}
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("detachedContext.Deadline() has a deadline")
+	}
+	if got := ctx.Value(key{}); got != "value" {
+		t.Errorf("detachedContext.Value() = %v, want %v", got, "value")
+	}
+}
+
+// Concurrent callers share a single execution of the Checker.
+func Test_check_run_coalesce(t *testing.T) {
+	testErr := errors.New("check.run coalesce error")
+	var (
+		mutex sync.Mutex
+		runs  int
+	)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := newCheck(func(_ context.Context) error {
+		mutex.Lock()
+		runs++
+		mutex.Unlock()
+		close(started)
+		<-release
+		return testErr
+	}, time.Second)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.check(context.Background())
+	}()
+	<-started
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.check(context.Background())
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if runs != 1 {
+		t.Errorf("check.run() runs = %v, want %v", runs, 1)
+	}
+	if r := c.result(); r.runs != 1 || r.errorsInARow != 1 {
+		t.Errorf("check.run() runs, errorsInARow = %v, %v, want %v, %v", r.runs, r.errorsInARow, 1, 1)
+	}
+}
+
+func Test_check_check_staleWhileRevalidate(t *testing.T) {
+	testErr := errors.New("check.check stale while revalidate error")
+	var (
+		mutex sync.Mutex
+		err   error
+		runs  int
+	)
+	release := make(chan struct{})
+	c := newCheck(func(_ context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		runs++
+		if runs > 1 {
+			<-release
+		}
+		return err
+	}, time.Second, WithStaleWhileRevalidate(time.Millisecond))
+	runsNow := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return runs
+	}
+
+	// First execution is synchronous.
+	if got := c.check(context.Background()); got != nil {
+		t.Errorf("check() = %v, want nil", got)
+	}
+	if got := runsNow(); got != 1 {
+		t.Errorf("check() runs = %v, want 1", got)
+	}
+
+	// Stale result returns immediately and triggers one refresh.
+	mutex.Lock()
+	err = testErr
+	mutex.Unlock()
+	time.Sleep(2 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if got := c.check(context.Background()); got != nil {
+			t.Errorf("check() = %v, want stale nil", got)
+		}
+	}
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	if got := runsNow(); got != 2 {
+		t.Errorf("check() runs = %v, want 2", got)
+	}
+	if got := c.state(); got != testErr {
+		t.Errorf("check() refreshed state = %v, want %v", got, testErr)
+	}
+}
+
+func Test_check_state(t *testing.T) {
+	testErr := errors.New("check.state error")
+	type fields struct {
+		threshold    uint
+		err          error
+		lastErr      error
+		failed       bool
+		errorsInARow uint
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   error
+	}{
+		{
+			"healthy",
+			fields{},
+			nil,
+		},
+		{
+			"unhealthy",
+			fields{err: testErr, errorsInARow: 1},
+			testErr,
+		},
+		{
+			"threshold_not_passed",
+			fields{threshold: 2, err: testErr, errorsInARow: 1},
+			nil,
+		},
+		{
+			"threshold_passed",
+			fields{threshold: 2, err: testErr, errorsInARow: 2},
+			testErr,
+		},
+		{
+			"recovering",
+			fields{lastErr: testErr, failed: true},
+			testErr,
+		},
+		{
+			"failed_under_threshold",
+			fields{threshold: 2, err: testErr, failed: true, errorsInARow: 1},
+			testErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				checker: func(_ context.Context) error {
+					t.Error("state() should not run the checker")
+					return nil
+				},
+				threshold:    tt.fields.threshold,
+				err:          tt.fields.err,
+				lastErr:      tt.fields.lastErr,
+				failed:       tt.fields.failed,
+				errorsInARow: tt.fields.errorsInARow,
+			}
+			if got := c.state(); got != tt.want {
+				t.Errorf("state() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_check_state_initial(t *testing.T) {
+	testErr := errors.New("check.state initial error")
+	tests := []struct {
+		name    string
+		initial Status
+		results []error
+		want    Status
+	}{
+		{"unknown", StatusUnknown, nil, StatusUnknown},
+		{"unknown_under_threshold", StatusUnknown, []error{testErr}, StatusUnknown},
+		{"unknown_threshold_passed", StatusUnknown, []error{testErr, testErr}, StatusUnhealthy},
+		{"unknown_passed", StatusUnknown, []error{nil, testErr}, StatusHealthy},
+		{"healthy", StatusHealthy, nil, StatusHealthy},
+		{"healthy_under_threshold", StatusHealthy, []error{testErr}, StatusHealthy},
+		{"unhealthy", StatusUnhealthy, nil, StatusUnhealthy},
+		{"unhealthy_passed", StatusUnhealthy, []error{nil}, StatusHealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCheck(func(_ context.Context) error { return nil }, time.Second, WithThreshold(2), WithInitialState(tt.initial))
+			for i := range tt.results {
+				c.record(tt.results[i], time.Now(), 0)
+			}
+			err := c.state()
+			if got := errStatus(err); got != tt.want {
+				t.Errorf("state() status = %v, want %v", got, tt.want)
+			}
+			if err != nil && len(tt.results) > 0 && !errors.Is(err, testErr) {
+				t.Errorf("state() = %v, want it to wrap %v", err, testErr)
+			}
+		})
+	}
+}
+
+func Test_errStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want Status
+	}{
+		{"healthy", nil, StatusHealthy},
+		{"unknown", errNeverChecked, StatusUnknown},
+		{"unhealthy", errors.New("errStatus error"), StatusUnhealthy},
+		{"unknown_under_threshold", &unknownError{errors.New("errStatus error")}, StatusUnknown},
+		{"assumed_unhealthy", errAssumedUnhealthy, StatusUnhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errStatus(tt.err); got != tt.want {
+				t.Errorf("errStatus() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_check_staleErr(t *testing.T) {
+	now := time.Now()
+	type fields struct {
+		interval       time.Duration
+		timeout        time.Duration
+		staleIntervals float64
+		created        time.Time
+		firstRun       time.Time
+		lastRun        time.Time
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   bool
+	}{
+		{
+			"disabled",
+			fields{interval: time.Second, lastRun: now.Add(-time.Hour)},
+			false,
+		},
+		{
+			"synchronous",
+			fields{staleIntervals: 2, lastRun: now.Add(-time.Hour)},
+			false,
+		},
+		{
+			"never_scheduled",
+			fields{interval: time.Second, timeout: time.Second, staleIntervals: 2, created: now.Add(-time.Hour)},
+			false,
+		},
+		{
+			"never_run",
+			fields{interval: time.Second, timeout: time.Second, staleIntervals: 2, created: now.Add(-time.Hour), firstRun: now.Add(-2500 * time.Millisecond)},
+			false,
+		},
+		{
+			"never_run_stale",
+			fields{interval: time.Second, timeout: time.Second, staleIntervals: 2, created: now.Add(-time.Hour), firstRun: now.Add(-20 * time.Second)},
+			true,
+		},
+		{
+			"fresh",
+			fields{interval: time.Second, timeout: time.Second, staleIntervals: 2, lastRun: now.Add(-2500 * time.Millisecond)},
+			false,
+		},
+		{
+			"stale",
+			fields{interval: time.Second, timeout: time.Second, staleIntervals: 2, lastRun: now.Add(-4 * time.Second)},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				interval:       tt.fields.interval,
+				timeout:        tt.fields.timeout,
+				staleIntervals: tt.fields.staleIntervals,
+				created:        tt.fields.created,
+				firstRun:       tt.fields.firstRun,
+				lastRun:        tt.fields.lastRun,
+			}
+			if err := c.staleErr(now); errors.Is(err, errStale) != tt.want {
+				t.Errorf("staleErr() = %v, want stale %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// A long backoff does not overflow intervals.
+func Test_check_backoffOverflow(t *testing.T) {
+	c := &check{
+		interval:       time.Second,
+		jitterRatio:    0.5,
+		backoffFactor:  2,
+		errorsInARow:   40,
+		staleIntervals: 2,
+		timeout:        time.Second,
+		lastRun:        time.Now().Add(-time.Hour),
+	}
+	if got := c.nextInterval(); got < maxDuration/2 {
+		t.Errorf("nextInterval() = %v, want at least %v", got, maxDuration/2)
+	}
+	if err := c.staleErr(time.Now()); err != nil {
+		t.Errorf("staleErr() = %v, want nil", err)
+	}
+}
+
+func Test_check_state_stale(t *testing.T) {
+	testErr := errors.New("check.state stale error")
+	c := &check{
+		interval:       time.Millisecond,
+		staleIntervals: 1,
+		lastRun:        time.Now().Add(-time.Second),
+	}
+	if err := c.state(); !errors.Is(err, errStale) {
+		t.Errorf("state() = %v, want %v", err, errStale)
+	}
+	c.err = testErr
+	c.errorsInARow = 1
+	if err := c.state(); err != testErr {
+		t.Errorf("state() = %v, want %v", err, testErr)
+	}
+}
+
+// Listeners are notified when a result gets stale and when it is fresh again.
+func Test_check_refresh_stale(t *testing.T) {
+	type change struct {
+		old Status
+		new Status
+	}
+	var got []change
+	c := newCheck(func(_ context.Context) error { return nil }, time.Second,
+		InBackground(time.Second), WithStaleResult(1))
+	c.onStatusChange = func(old, new Status, _ error, _ time.Time) {
+		got = append(got, change{old, new})
+	}
+	c.run(context.Background())
+	c.refresh(time.Now())
+	c.refresh(time.Now().Add(time.Minute))
+	c.refresh(time.Now().Add(time.Minute))
+	c.run(context.Background())
+	want := []change{
+		{StatusUnknown, StatusHealthy},
+		{StatusHealthy, StatusUnhealthy},
+		{StatusUnhealthy, StatusHealthy},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("check.refresh() status changes = %v, want %v", got, want)
+	}
+}
+
+// A background check assumed healthy gets stale if it never runs.
+func Test_check_state_staleNeverRun(t *testing.T) {
+	c := newCheck(func(_ context.Context) error { return nil }, time.Millisecond,
+		InBackground(time.Millisecond), WithStaleResult(2), WithInitialState(StatusHealthy))
+	c.schedule(time.Now())
+	if err := c.state(); err != nil {
+		t.Errorf("state() = %v, want nil", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := c.state(); !errors.Is(err, errStale) {
+		t.Errorf("state() = %v, want %v", err, errStale)
+	}
+}
+
+func Test_check_run(t *testing.T) {
+	testErr := errors.New("check.run error")
+	checkerCreator := func(err error) checkerWithTimeout {
+		return func(_ context.Context) error {
+			return err
+		}
+	}
+	type fields struct {
+		checker      checkerWithTimeout
+		timeout      time.Duration
+		interval     time.Duration
+		threshold    uint
+		err          error
+		errorsInARow uint
+	}
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name                string
+		fields              fields
+		args                args
+		wantErr             error
+		wantErrorsInARow    uint
+		wantSuccessesInARow uint
+		wantFailures        uint64
+	}{
+		{
+			"with_error",
+			fields{
+				checker:      checkerCreator(testErr),
+				err:          nil,
+				errorsInARow: 0,
+			},
+			args{context.Background()},
+			testErr,
+			1,
+			0,
+			1,
+		},
+		{
+			"without_error",
+			fields{
+				checker:      checkerCreator(nil),
+				err:          testErr,
+				errorsInARow: 2,
+			},
+			args{context.Background()},
+			nil,
+			0,
+			1,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				checker:      tt.fields.checker,
+				timeout:      tt.fields.timeout,
+				interval:     tt.fields.interval,
+				threshold:    tt.fields.threshold,
+				err:          tt.fields.err,
+				errorsInARow: tt.fields.errorsInARow,
+			}
+			c.run(tt.args.ctx)
+			if c.err != tt.wantErr {
+				t.Errorf("check.run() err = %v, want %v", c.err, tt.wantErr)
+			}
+			if c.errorsInARow != tt.wantErrorsInARow {
+				t.Errorf("check.run() errorsInARow = %v, want %v", c.errorsInARow, tt.wantErrorsInARow)
+			}
+			r := c.result()
+			if r.successesInARow != tt.wantSuccessesInARow {
+				t.Errorf("check.run() successesInARow = %v, want %v", r.successesInARow, tt.wantSuccessesInARow)
+			}
+			if r.runs != 1 {
+				t.Errorf("check.run() runs = %v, want %v", r.runs, 1)
+			}
+			if r.failures != tt.wantFailures {
+				t.Errorf("check.run() failures = %v, want %v", r.failures, tt.wantFailures)
+			}
+			if r.lastRun.IsZero() {
+				t.Error("check.run() lastRun is not set")
+			}
+			if (tt.wantErr == nil) == r.lastSuccess.IsZero() {
+				t.Errorf("check.run() lastSuccess = %v, want set %v", r.lastSuccess, tt.wantErr == nil)
+			}
+			if (tt.wantErr != nil) == r.lastFailure.IsZero() {
+				t.Errorf("check.run() lastFailure = %v, want set %v", r.lastFailure, tt.wantErr != nil)
+			}
+		})
+	}
+}
+
+func Test_check_run_onStatusChange(t *testing.T) {
+	testErr := errors.New("check.run onStatusChange error")
+	type change struct {
+		old Status
+		new Status
+		err error
+	}
+	tests := []struct {
+		name      string
+		threshold uint
+		recovery  uint
+		window    uint
+		errs      []error
+		want      []change
+	}{
+		{
+			"no_change",
+			0,
+			0,
+			0,
+			[]error{nil, nil},
+			nil,
+		},
+		{
+			"fail_and_recover",
+			0,
+			0,
+			0,
+			[]error{nil, testErr, testErr, nil},
+			[]change{
+				{StatusHealthy, StatusUnhealthy, testErr},
+				{StatusUnhealthy, StatusHealthy, nil},
+			},
+		},
+		{
+			"with_threshold",
+			2,
+			0,
+			0,
+			[]error{testErr, nil, testErr, testErr},
+			[]change{
+				{StatusHealthy, StatusUnhealthy, testErr},
+			},
+		},
+		{
+			"with_failure_ratio",
+			10,
+			0,
+			4,
+			[]error{testErr, nil, testErr, testErr, nil},
+			[]change{
+				{StatusHealthy, StatusUnhealthy, testErr},
+				{StatusUnhealthy, StatusHealthy, nil},
+			},
+		},
+		{
+			"with_failure_ratio_filled_on_success",
+			10,
+			0,
+			3,
+			[]error{testErr, testErr, nil},
+			[]change{
+				{StatusHealthy, StatusUnhealthy, testErr},
+			},
+		},
+		{
+			"with_recovery_threshold",
+			0,
+			3,
+			0,
+			[]error{testErr, nil, nil, testErr, nil, nil, nil},
+			[]change{
+				{StatusHealthy, StatusUnhealthy, testErr},
+				{StatusUnhealthy, StatusHealthy, nil},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				i   int
+				got []change
+			)
+			c := &check{
+				checker: func(_ context.Context) error {
+					return tt.errs[i]
+				},
+				threshold: tt.threshold,
+				recovery:  tt.recovery,
+				onStatusChange: func(old, new Status, err error, _ time.Time) {
+					got = append(got, change{old, new, err})
+				},
+			}
+			WithFailureRatio(tt.window, 0.5)(c)
+			for i = range tt.errs {
+				c.run(context.Background())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("check.run() status changes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_check_isInBackground(t *testing.T) {
+	type fields struct {
+		interval time.Duration
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		want   bool
+	}{
+		{
+			"in_background",
+			fields{
+				time.Minute,
+			},
+			true,
+		},
+		{
+			"not_in_background",
+			fields{},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &check{
+				interval: tt.fields.interval,
+			}
+			if got := c.isInBackground(); got != tt.want {
+				t.Errorf("isInBackground() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_check_hasTag(t *testing.T) {
+	type fields struct {
+		tags []string
 	}
 	type args struct {
-		ctx context.Context
+		tags []string
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		args   args
-		want   error
+		want   bool
 	}{
 		{
-			"simple",
-			fields{
-				checker: checkerCreator(testErr),
-				err:     nil,
-			},
-			args{context.Background()},
-			testErr,
+			"no_tags",
+			fields{},
+			args{},
+			true,
 		},
 		{
-			"with_threshold_not_passed",
-			fields{
-				checker:     checkerCreator(testErr),
-				err:         testErr,
-				threshold:   3,
-				errorsOnRow: 1,
-			},
-			args{context.Background()},
-			nil,
+			"untagged_check",
+			fields{},
+			args{[]string{"readiness"}},
+			false,
 		},
 		{
-			"with_threshold_passed",
-			fields{
-				checker:     checkerCreator(testErr),
-				err:         testErr,
-				threshold:   2,
-				errorsOnRow: 1,
-			},
-			args{context.Background()},
-			testErr,
+			"no_filter",
+			fields{[]string{"readiness"}},
+			args{},
+			true,
 		},
 		{
-			"in_background",
-			fields{
-				checker:  checkerCreator(nil),
-				err:      testErr,
-				interval: time.Second,
-			},
-			args{context.Background()},
-			testErr,
+			"match",
+			fields{[]string{"liveness", "readiness"}},
+			args{[]string{"startup", "readiness"}},
+			true,
 		},
 		{
-			"in_background_with_threshold_not_passed",
-			fields{
-				checker:   checkerCreator(testErr),
-				err:       testErr,
-				threshold: 1,
-				interval:  time.Second,
-			},
-			args{context.Background()},
-			nil,
+			"no_match",
+			fields{[]string{"liveness"}},
+			args{[]string{"readiness"}},
+			false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &check{
-				checker:      tt.fields.checker,
-				timeout:      tt.fields.timeout,
-				interval:     tt.fields.interval,
-				threshold:    tt.fields.threshold,
-				err:          tt.fields.err,
-				errorsInARow: tt.fields.errorsOnRow,
+				tags: tt.fields.tags,
 			}
-			if got := c.check(tt.args.ctx); got != tt.want {
-				t.Errorf("check() got = %v, want %v", got, tt.want)
+			if got := c.hasTag(tt.args.tags); got != tt.want {
+				t.Errorf("hasTag() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_check_run(t *testing.T) {
-	testErr := errors.New("check.run error")
-	checkerCreator := func(err error) checkerWithTimeout {
-		return func(_ context.Context) error {
-			return err
-		}
-	}
+func Test_check_nextInterval(t *testing.T) {
 	type fields struct {
-		checker      checkerWithTimeout
-		timeout      time.Duration
-		interval     time.Duration
-		threshold    uint
-		err          error
-		errorsInARow uint
-	}
-	type args struct {
-		ctx context.Context
+		interval    time.Duration
+		jitter      time.Duration
+		jitterRatio float64
 	}
 	tests := []struct {
-		name             string
-		fields           fields
-		args             args
-		wantErr          error
-		wantErrorsInARow uint
+		name   string
+		fields fields
+		min    time.Duration
+		max    time.Duration
 	}{
 		{
-			"with_error",
-			fields{
-				checker:      checkerCreator(testErr),
-				err:          nil,
-				errorsInARow: 0,
-			},
-			args{context.Background()},
-			testErr,
-			1,
+			"without_jitter",
+			fields{interval: time.Minute},
+			time.Minute,
+			time.Minute,
 		},
 		{
-			"with_error",
-			fields{
-				checker:      checkerCreator(nil),
-				err:          testErr,
-				errorsInARow: 2,
-			},
-			args{context.Background()},
-			nil,
-			0,
+			"with_jitter",
+			fields{interval: time.Minute, jitter: time.Second},
+			time.Minute - time.Second,
+			time.Minute + time.Second,
+		},
+		{
+			"with_jitter_ratio",
+			fields{interval: time.Minute, jitterRatio: 0.5},
+			30 * time.Second,
+			90 * time.Second,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &check{
-				checker:      tt.fields.checker,
-				timeout:      tt.fields.timeout,
-				interval:     tt.fields.interval,
-				threshold:    tt.fields.threshold,
-				err:          tt.fields.err,
-				errorsInARow: tt.fields.errorsInARow,
-			}
-			c.run(tt.args.ctx)
-			if c.err != tt.wantErr {
-				t.Errorf("check.run() err = %v, want %v", c.err, tt.wantErr)
+				interval:    tt.fields.interval,
+				jitter:      tt.fields.jitter,
+				jitterRatio: tt.fields.jitterRatio,
 			}
-			if c.errorsInARow != tt.wantErrorsInARow {
-				t.Errorf("check.run() errorsInARow = %v, want %v", c.errorsInARow, tt.wantErrorsInARow)
+			for i := 0; i < 100; i++ {
+				if got := c.nextInterval(); got < tt.min || got > tt.max {
+					t.Fatalf("nextInterval() = %v, want in [%v, %v]", got, tt.min, tt.max)
+				}
 			}
 		})
 	}
 }
 
-func Test_check_isInBackground(t *testing.T) {
+func Test_check_effectiveInterval(t *testing.T) {
 	type fields struct {
-		interval time.Duration
+		unhealthyInterval time.Duration
+		backoffFactor     float64
+		backoffMax        time.Duration
+		err               error
+		lastErr           error
+		errorsInARow      uint
+		failed            bool
 	}
 	tests := []struct {
 		name   string
 		fields fields
-		want   bool
+		want   time.Duration
 	}{
 		{
-			"in_background",
-			fields{
-				time.Minute,
-			},
-			true,
+			"without_backoff",
+			fields{errorsInARow: 3},
+			time.Second,
 		},
 		{
-			"not_in_background",
-			fields{},
-			false,
+			"healthy",
+			fields{backoffFactor: 2, backoffMax: time.Minute},
+			time.Second,
+		},
+		{
+			"failing",
+			fields{backoffFactor: 2, backoffMax: time.Minute, errorsInARow: 3},
+			8 * time.Second,
+		},
+		{
+			"capped",
+			fields{backoffFactor: 2, backoffMax: time.Minute, errorsInARow: 10},
+			time.Minute,
+		},
+		{
+			"without_cap",
+			fields{backoffFactor: 1.5, errorsInARow: 2},
+			2250 * time.Millisecond,
+		},
+		{
+			"without_cap_overflow",
+			fields{backoffFactor: 2, errorsInARow: 1000},
+			maxDuration,
+		},
+		{
+			"with_cap_overflow",
+			fields{backoffFactor: 2, backoffMax: time.Hour, errorsInARow: 1000},
+			time.Hour,
+		},
+		{
+			"adaptive_healthy",
+			fields{unhealthyInterval: 100 * time.Millisecond},
+			time.Second,
+		},
+		{
+			"adaptive_unhealthy",
+			fields{unhealthyInterval: 100 * time.Millisecond, err: errors.New("check.effectiveInterval error"), errorsInARow: 1},
+			100 * time.Millisecond,
+		},
+		{
+			"adaptive_recovering",
+			fields{unhealthyInterval: 100 * time.Millisecond, lastErr: errors.New("check.effectiveInterval error"), failed: true},
+			100 * time.Millisecond,
+		},
+		{
+			"adaptive_with_backoff",
+			fields{unhealthyInterval: 100 * time.Millisecond, backoffFactor: 2, err: errors.New("check.effectiveInterval error"), errorsInARow: 2},
+			400 * time.Millisecond,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &check{
-				interval: tt.fields.interval,
+				interval:          time.Second,
+				unhealthyInterval: tt.fields.unhealthyInterval,
+				backoffFactor:     tt.fields.backoffFactor,
+				backoffMax:        tt.fields.backoffMax,
+				err:               tt.fields.err,
+				lastErr:           tt.fields.lastErr,
+				errorsInARow:      tt.fields.errorsInARow,
+				failed:            tt.fields.failed,
 			}
-			if got := c.isInBackground(); got != tt.want {
-				t.Errorf("isInBackground() = %v, want %v", got, tt.want)
+			if got := c.effectiveInterval(); got != tt.want {
+				t.Errorf("effectiveInterval() = %v, want %v", got, tt.want)
+			}
+			if got := c.nextInterval(); got != tt.want {
+				t.Errorf("nextInterval() = %v, want %v", got, tt.want)
+			}
+			if got := c.result().interval; got != tt.want {
+				t.Errorf("result().interval = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-// We can't compare actual value in here. Only check if function works.
-func Test_check_ticker(t *testing.T) {
+func Test_check_initialDelay(t *testing.T) {
 	type fields struct {
 		interval time.Duration
+		delay    time.Duration
+		stagger  bool
 	}
 	tests := []struct {
 		name   string
 		fields fields
+		min    time.Duration
+		max    time.Duration
 	}{
 		{
-			"simple",
-			fields{
-				interval: time.Minute,
-			},
+			"no_delay",
+			fields{interval: time.Minute},
+			0,
+			0,
+		},
+		{
+			"delay",
+			fields{interval: time.Minute, delay: time.Second},
+			time.Second,
+			time.Second,
+		},
+		{
+			"stagger",
+			fields{interval: time.Minute, delay: time.Second, stagger: true},
+			time.Second,
+			time.Minute + time.Second,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			c := &check{
 				interval: tt.fields.interval,
+				delay:    tt.fields.delay,
+				stagger:  tt.fields.stagger,
 			}
-			if got := c.ticker(); got == nil {
-				t.Errorf("ticker() want *time.Ticker, got %v", got)
+			for i := 0; i < 100; i++ {
+				if got := c.initialDelay(); got < tt.min || got > tt.max {
+					t.Fatalf("initialDelay() = %v, want in [%v, %v]", got, tt.min, tt.max)
+				}
 			}
 		})
 	}
 }
 
+func Test_addJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		interval time.Duration
+		jitter   time.Duration
+		min      time.Duration
+		max      time.Duration
+	}{
+		{
+			"no_jitter",
+			time.Second,
+			0,
+			time.Second,
+			time.Second,
+		},
+		{
+			"jitter",
+			time.Second,
+			100 * time.Millisecond,
+			900 * time.Millisecond,
+			1100 * time.Millisecond,
+		},
+		{
+			"never_negative",
+			time.Millisecond,
+			time.Second,
+			0,
+			time.Second + time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				if got := addJitter(tt.interval, tt.jitter); got < tt.min || got > tt.max {
+					t.Fatalf("addJitter() = %v, want in [%v, %v]", got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func Test_randomDuration(t *testing.T) {
+	if got := randomDuration(0); got != 0 {
+		t.Errorf("randomDuration(0) = %v, want 0", got)
+	}
+	for i := 0; i < 100; i++ {
+		if got := randomDuration(time.Second); got < 0 || got >= time.Second {
+			t.Fatalf("randomDuration() = %v, want in [0, %v)", got, time.Second)
+		}
+	}
+}
+
 func Test_newChecker(t *testing.T) {
 	testErr := errors.New("newCheck error")
 	type args struct {
@@ -311,6 +1492,7 @@ func Test_newChecker(t *testing.T) {
 			&check{
 				timeout: time.Minute,
 				err:     errNeverChecked,
+				initial: StatusUnknown,
 			},
 		},
 		{
@@ -323,6 +1505,7 @@ func Test_newChecker(t *testing.T) {
 			&check{
 				timeout:  time.Minute,
 				err:      errNeverChecked,
+				initial:  StatusUnknown,
 				interval: time.Hour,
 			},
 		},
@@ -334,10 +1517,37 @@ func Test_newChecker(t *testing.T) {
 				[]CheckOption{WithThreshold(5)},
 			},
 			&check{
-				timeout:      time.Minute,
-				err:          errNeverChecked,
-				threshold:    5,
-				errorsInARow: 5,
+				timeout:   time.Minute,
+				err:       errNeverChecked,
+				initial:   StatusUnknown,
+				threshold: 5,
+			},
+		},
+		{
+			"initial_healthy",
+			args{
+				func(_ context.Context) error { return testErr },
+				time.Minute,
+				[]CheckOption{WithInitialState(StatusHealthy)},
+			},
+			&check{
+				timeout: time.Minute,
+				initial: StatusHealthy,
+			},
+		},
+		{
+			"initial_unhealthy",
+			args{
+				func(_ context.Context) error { return testErr },
+				time.Minute,
+				[]CheckOption{WithInitialState(StatusUnhealthy)},
+			},
+			&check{
+				timeout: time.Minute,
+				err:     errAssumedUnhealthy,
+				lastErr: errAssumedUnhealthy,
+				failed:  true,
+				initial: StatusUnhealthy,
 			},
 		},
 	}
@@ -347,7 +1557,15 @@ func Test_newChecker(t *testing.T) {
 			if err := got.checker(context.Background()); err != testErr {
 				t.Errorf("newCheck().check() = %v, want %v", err, testErr)
 			}
+			if got.hang == nil {
+				t.Error("newCheck().hang is nil")
+			}
+			if got.created.IsZero() {
+				t.Error("newCheck().created is zero")
+			}
 			got.checker = nil
+			got.hang = nil
+			got.created = time.Time{}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("newCheck() = %v, want %v", got, tt.want)
 			}
@@ -355,6 +1573,167 @@ func Test_newChecker(t *testing.T) {
 	}
 }
 
+func Test_newCheckerWithTimeout_panic(t *testing.T) {
+	c := newCheckerWithTimeout(func(_ context.Context) error {
+		panic("checker panic")
+	}, time.Second, &hangTracker{})
+	err := c(context.Background())
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("newCheckerWithTimeout()().error = %v, want a PanicError", err)
+	}
+	if panicErr.Value != "checker panic" {
+		t.Errorf("PanicError.Value = %v, want %v", panicErr.Value, "checker panic")
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Error("PanicError.Stack is empty")
+	}
+	if got, want := panicErr.Error(), "panic: checker panic"; got != want {
+		t.Errorf("PanicError.Error() = %v, want %v", got, want)
+	}
+}
+
+// A Checker that exits without a panic value is reported as a PanicError.
+func Test_newCheckerWithTimeout_panicWithoutValue(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Checker
+	}{
+		{
+			"panic_nil",
+			func(_ context.Context) error {
+				panic(nil)
+			},
+		},
+		{
+			"goexit",
+			func(_ context.Context) error {
+				runtime.Goexit()
+				return nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCheckerWithTimeout(tt.c, time.Second, &hangTracker{})
+			var panicErr *PanicError
+			if err := c(context.Background()); !errors.As(err, &panicErr) {
+				t.Errorf("newCheckerWithTimeout()().error = %v, want a PanicError", err)
+			}
+		})
+	}
+}
+
+func Test_check_run_panic(t *testing.T) {
+	c := newCheck(func(_ context.Context) error {
+		var m map[string]int
+		m["panic"]++
+		return nil
+	}, time.Second)
+	c.run(context.Background())
+	c.run(context.Background())
+	r := c.result()
+	if r.panics != 2 || r.failures != 2 {
+		t.Errorf("check.run() panics, failures = %v, %v, want %v, %v", r.panics, r.failures, 2, 2)
+	}
+}
+
+// A hung Checker is abandoned once and no new execution starts until it returns.
+// A late finish of an execution does not finish the next one.
+func Test_hangTracker_lateFinish(t *testing.T) {
+	hang := &hangTracker{}
+	first, err := hang.start(time.Now())
+	if err != nil {
+		t.Fatalf("hangTracker.start() = %v, want nil", err)
+	}
+	if _, err := hang.start(time.Now()); err != nil {
+		t.Fatalf("hangTracker.start() = %v, want nil", err)
+	}
+	hang.finish(first)
+	hang.abandon()
+	if got := hang.abandonedCount(); got != 1 {
+		t.Errorf("hangTracker.abandonedCount() = %v, want %v", got, 1)
+	}
+	if _, err := hang.start(time.Now()); !errors.Is(err, errStillRunning) {
+		t.Errorf("hangTracker.start() = %v, want %v", err, errStillRunning)
+	}
+}
+
+// A hung execution right after a successful one is still tracked.
+func Test_newCheckerWithTimeout_hungAfterSuccess(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		var calls int32
+		release := make(chan struct{})
+		hang := &hangTracker{}
+		c := newCheckerWithTimeout(func(_ context.Context) error {
+			if atomic.AddInt32(&calls, 1) > 1 {
+				<-release
+			}
+			return nil
+		}, time.Millisecond, hang)
+		if err := c(context.Background()); err != nil {
+			t.Fatalf("newCheckerWithTimeout()().error = %v, want nil", err)
+		}
+		hang.mutex.Lock()
+		running := hang.running
+		hang.mutex.Unlock()
+		if running {
+			t.Fatal("hangTracker.running = true after the result is delivered, want false")
+		}
+		if err := c(context.Background()); err != errTimeout {
+			t.Fatalf("newCheckerWithTimeout()().error = %v, want %v", err, errTimeout)
+		}
+		if err := c(context.Background()); !errors.Is(err, errStillRunning) {
+			t.Fatalf("newCheckerWithTimeout()().error = %v, want %v", err, errStillRunning)
+		}
+		if got := hang.abandonedCount(); got != 1 {
+			t.Fatalf("hangTracker.abandonedCount() = %v, want %v", got, 1)
+		}
+		close(release)
+	}
+}
+
+func Test_newCheckerWithTimeout_hung(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		runs  int
+	)
+	release := make(chan struct{})
+	hang := &hangTracker{}
+	c := newCheckerWithTimeout(func(_ context.Context) error {
+		mutex.Lock()
+		runs++
+		mutex.Unlock()
+		<-release
+		return nil
+	}, time.Millisecond, hang)
+	if err := c(context.Background()); err != errTimeout {
+		t.Errorf("newCheckerWithTimeout()().error = %v, want %v", err, errTimeout)
+	}
+	for i := 0; i < 3; i++ {
+		if err := c(context.Background()); !errors.Is(err, errStillRunning) {
+			t.Errorf("newCheckerWithTimeout()().error = %v, want %v", err, errStillRunning)
+		}
+	}
+	mutex.Lock()
+	if runs != 1 {
+		t.Errorf("newCheckerWithTimeout() runs = %v, want %v", runs, 1)
+	}
+	mutex.Unlock()
+	if got := hang.abandonedCount(); got != 1 {
+		t.Errorf("hangTracker.abandonedCount() = %v, want %v", got, 1)
+	}
+
+	close(release)
+	time.Sleep(10 * time.Millisecond)
+	if err := c(context.Background()); err != nil {
+		t.Errorf("newCheckerWithTimeout()().error = %v, want nil", err)
+	}
+	if got := hang.abandonedCount(); got != 1 {
+		t.Errorf("hangTracker.abandonedCount() = %v, want %v", got, 1)
+	}
+}
+
 func Test_newCheckerWithTimeout(t *testing.T) {
 	checkerCreator := func(sleep time.Duration) Checker {
 		return func(_ context.Context) error {
@@ -390,7 +1769,7 @@ func Test_newCheckerWithTimeout(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := newCheckerWithTimeout(tt.args.c, tt.args.timeout)
+			c := newCheckerWithTimeout(tt.args.c, tt.args.timeout, &hangTracker{})
 			if err := c(context.Background()); err != tt.want {
 				t.Errorf("newCheckerWithTimeout()().error = %v, want %v", err, tt.want)
 			}
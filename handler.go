@@ -4,39 +4,227 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 )
 
+// application/health+json format constants.
+const (
+	// healthJSONContentType is the content type of application/health+json format.
+	healthJSONContentType = "application/health+json"
+	// healthJSONFormat is the value of format query parameter to request application/health+json format.
+	healthJSONFormat = "health"
+)
+
+// application/health+json statuses.
+const (
+	healthJSONPass = "pass"
+	healthJSONWarn = "warn"
+	healthJSONFail = "fail"
+)
+
+// A detail is the detailed response of handler.
+type detail struct {
+	Status Status                 `json:"status"`
+	Checks map[string]checkDetail `json:"checks"`
+}
+
+// A checkDetail is the detail of a checker in the detailed response.
+type checkDetail struct {
+	Status          Status     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	LastRun         *time.Time `json:"lastRun,omitempty"`
+	Age             string     `json:"age,omitempty"`
+	LastSuccess     *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure     *time.Time `json:"lastFailure,omitempty"`
+	LastDuration    string     `json:"lastDuration"`
+	Runs            uint64     `json:"runs"`
+	Failures        uint64     `json:"failures"`
+	Panics          uint64     `json:"panics"`
+	Abandoned       uint64     `json:"abandoned"`
+	ErrorsInARow    uint       `json:"errorsInARow"`
+	SuccessesInARow uint       `json:"successesInARow"`
+	FailureRatio    *float64   `json:"failureRatio,omitempty"`
+	Interval        string     `json:"interval,omitempty"`
+}
+
+// A healthJSON is the response of handler in application/health+json format.
+// https://tools.ietf.org/html/draft-inadarei-api-health-check
+type healthJSON struct {
+	Status string                       `json:"status"`
+	Checks map[string][]healthJSONCheck `json:"checks"`
+}
+
+// A healthJSONCheck is the detail of a checker in application/health+json format.
+// observedValue is the last duration of the check in milliseconds.
+type healthJSONCheck struct {
+	ComponentType string     `json:"componentType"`
+	ObservedValue float64    `json:"observedValue"`
+	ObservedUnit  string     `json:"observedUnit"`
+	Status        string     `json:"status"`
+	Time          *time.Time `json:"time,omitempty"`
+	Output        string     `json:"output,omitempty"`
+}
+
 // handler will handle health check requests.
-// Return 200 if all checkers pass, otherwise 503.
+// Return 503 if any Critical checker fails or is unknown, otherwise 200. Failing NonCritical checkers make the status degraded.
 // If no parameter set, handler will only return the status code and no body.
-// If detail query parameter set, it will show the detail of each checker and
-// their errors, or OK status. The body is in JSON format.
+// If detail query parameter set, it will show the overall status and the detail of each checker:
+// its status, error, last run, age of the result, success and failure times, last duration, run, failure, panic and abandoned execution counters, failure ratio and the current interval of background checkers.
+// The body is in JSON format.
+// If Accept header contains application/health+json or format query parameter is health,
+// the body is in application/health+json format.
 func (h *HealthCheck) handler(w http.ResponseWriter, r *http.Request) {
+	h.handle(w, r)
+}
+
+// handle handles health check requests for checkers carrying any of the tags, or all checkers if no tag is passed.
+func (h *HealthCheck) handle(w http.ResponseWriter, r *http.Request, tags ...string) {
 	ctx := r.Context()
-	errs := h.check(ctx)
-	if len(errs) == 0 {
-		w.WriteHeader(http.StatusOK)
-	} else {
+	errs := h.check(ctx, tags...)
+	status := h.status(errs)
+	healthJSON := acceptsHealthJSON(r)
+	if healthJSON {
+		w.Header().Set("Content-Type", healthJSONContentType)
+	}
+	if status == StatusUnhealthy || status == StatusUnknown {
 		w.WriteHeader(http.StatusServiceUnavailable)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	if healthJSON {
+		h.handlerHealthJSON(ctx, w, status, errs, tags)
+		return
 	}
 	_, ok := r.URL.Query()["detail"]
 	if ok {
-		h.handlerDetail(ctx, w, errs)
+		h.handlerDetail(ctx, w, status, errs, tags)
 	}
 }
 
-// handlerDetail writes json version of details of checkers to the response.
-func (h *HealthCheck) handlerDetail(_ context.Context, w http.ResponseWriter, errs map[string]error) {
-	result := make(map[string]string)
-	for name := range h.checkers {
-		err, ok := errs[name]
-		if ok {
-			result[name] = err.Error()
+// startupHandler creates a handler of startup probe requests for checkers carrying any of the tags.
+// Once all the checkers have succeeded, it latches and does not run checks anymore.
+// It never latches while there is no such checker.
+func (h *HealthCheck) startupHandler(tags []string) http.HandlerFunc {
+	var (
+		mutex   sync.Mutex
+		started bool
+	)
+	return func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if !started {
+			h.check(r.Context(), tags...)
+			started = h.succeeded(tags)
+		}
+		if started {
+			w.WriteHeader(http.StatusOK)
 		} else {
-			result[name] = "OK"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// acceptsHealthJSON shows if a request asks for application/health+json format.
+func acceptsHealthJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == healthJSONFormat {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), healthJSONContentType)
+}
+
+// handlerDetail writes json version of the status and details of checkers to the response.
+func (h *HealthCheck) handlerDetail(_ context.Context, w http.ResponseWriter, status Status, errs map[string]error, tags []string) {
+	response := detail{
+		Status: status,
+		Checks: make(map[string]checkDetail),
+	}
+	now := time.Now()
+	for name, checker := range h.tagged(tags) {
+		response.Checks[name] = newCheckDetail(checker.result(), errs[name], now)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "    ")
+	_ = encoder.Encode(response)
+}
+
+// newCheckDetail creates the detail of a checker from its result and its threshold adjusted error.
+// Age of the result is calculated at now.
+func newCheckDetail(r result, err error, now time.Time) checkDetail {
+	d := checkDetail{
+		Status:          StatusHealthy,
+		LastRun:         timeOrNil(r.lastRun),
+		LastSuccess:     timeOrNil(r.lastSuccess),
+		LastFailure:     timeOrNil(r.lastFailure),
+		LastDuration:    r.lastDuration.String(),
+		Runs:            r.runs,
+		Failures:        r.failures,
+		Panics:          r.panics,
+		Abandoned:       r.abandoned,
+		ErrorsInARow:    r.errorsInARow,
+		SuccessesInARow: r.successesInARow,
+		FailureRatio:    r.failureRatio,
+	}
+	if !r.lastRun.IsZero() {
+		d.Age = now.Sub(r.lastRun).String()
+	}
+	if r.interval > 0 {
+		d.Interval = r.interval.String()
+	}
+	if err != nil {
+		d.Status = errStatus(err)
+		d.Error = err.Error()
+	}
+	return d
+}
+
+// timeOrNil returns nil for zero time, so it can be omitted in JSON.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// handlerHealthJSON writes application/health+json version of the status and details of checkers to the response.
+func (h *HealthCheck) handlerHealthJSON(_ context.Context, w http.ResponseWriter, status Status, errs map[string]error, tags []string) {
+	response := healthJSON{
+		Status: healthJSONStatus(status),
+		Checks: make(map[string][]healthJSONCheck),
+	}
+	for name, checker := range h.tagged(tags) {
+		r := checker.result()
+		c := healthJSONCheck{
+			ComponentType: checker.componentType(),
+			ObservedValue: float64(r.lastDuration) / float64(time.Millisecond),
+			ObservedUnit:  "ms",
+			Status:        healthJSONPass,
+			Time:          timeOrNil(r.lastRun),
+		}
+		if err, ok := errs[name]; ok {
+			c.Output = err.Error()
+			if checker.isCritical() {
+				c.Status = healthJSONFail
+			} else {
+				c.Status = healthJSONWarn
+			}
 		}
+		response.Checks[name] = []healthJSONCheck{c}
 	}
 	encoder := json.NewEncoder(w)
 	encoder.SetIndent("", "    ")
-	_ = encoder.Encode(result)
+	_ = encoder.Encode(response)
+}
+
+// healthJSONStatus converts a Status to application/health+json status.
+func healthJSONStatus(status Status) string {
+	switch status {
+	case StatusHealthy:
+		return healthJSONPass
+	case StatusDegraded:
+		return healthJSONWarn
+	default:
+		return healthJSONFail
+	}
 }
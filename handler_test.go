@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestHealthCheck_handler(t *testing.T) {
@@ -18,8 +20,9 @@ func TestHealthCheck_handler(t *testing.T) {
 		r *http.Request
 	}
 	type want struct {
-		code int
-		body bool
+		code        int
+		body        bool
+		contentType string
 	}
 	tests := []struct {
 		name   string
@@ -34,6 +37,7 @@ func TestHealthCheck_handler(t *testing.T) {
 			want{
 				http.StatusOK,
 				false,
+				"",
 			},
 		},
 		{
@@ -46,6 +50,7 @@ func TestHealthCheck_handler(t *testing.T) {
 			want{
 				http.StatusOK,
 				false,
+				"",
 			},
 		},
 		{
@@ -58,6 +63,33 @@ func TestHealthCheck_handler(t *testing.T) {
 			want{
 				http.StatusServiceUnavailable,
 				false,
+				"",
+			},
+		},
+		{
+			"unknown",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{},
+				"checker_2": &mockCheck{err: errNeverChecked},
+			}},
+			args{httptest.NewRequest(http.MethodGet, "/metrics", nil)},
+			want{
+				http.StatusServiceUnavailable,
+				false,
+				"",
+			},
+		},
+		{
+			"degraded",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{},
+				"checker_2": &mockCheck{err: errors.New("checker_2 failed"), severity: NonCritical},
+			}},
+			args{httptest.NewRequest(http.MethodGet, "/metrics", nil)},
+			want{
+				http.StatusOK,
+				false,
+				"",
 			},
 		},
 		{
@@ -70,9 +102,43 @@ func TestHealthCheck_handler(t *testing.T) {
 			want{
 				http.StatusServiceUnavailable,
 				true,
+				"",
 			},
 		},
 	}
+	acceptHealthJSON := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	acceptHealthJSON.Header.Set("Accept", "application/health+json, application/json")
+	tests = append(tests, []struct {
+		name   string
+		fields fields
+		args   args
+		want   want
+	}{
+		{
+			"health_json_query",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{},
+			}},
+			args{httptest.NewRequest(http.MethodGet, "/metrics?format=health", nil)},
+			want{
+				http.StatusOK,
+				true,
+				"application/health+json",
+			},
+		},
+		{
+			"health_json_accept",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{err: errors.New("checker_1 failed")},
+			}},
+			args{acceptHealthJSON},
+			want{
+				http.StatusServiceUnavailable,
+				true,
+				"application/health+json",
+			},
+		},
+	}...)
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			w := httptest.NewRecorder()
@@ -86,6 +152,9 @@ func TestHealthCheck_handler(t *testing.T) {
 			if (w.Body.Len() != 0) != tt.want.body {
 				t.Errorf("handler() body = %v, want body %v", w.Body, tt.want.body)
 			}
+			if got := w.Header().Get("Content-Type"); tt.want.contentType != "" && got != tt.want.contentType {
+				t.Errorf("handler() Content-Type = %v, want %v", got, tt.want.contentType)
+			}
 		})
 	}
 }
@@ -95,23 +164,26 @@ func TestHealthCheck_handlerDetail(t *testing.T) {
 		checkers map[string]checker
 	}
 	type args struct {
-		ctx  context.Context
-		errs map[string]error
+		ctx    context.Context
+		status Status
+		errs   map[string]error
+		tags   []string
 	}
 	tests := []struct {
 		name   string
 		fields fields
 		args   args
-		want   map[string]string
+		want   detail
 	}{
 		{
 			"empty",
 			fields{map[string]checker{}},
 			args{
-				context.Background(),
-				map[string]error{},
+				ctx:    context.Background(),
+				status: StatusHealthy,
+				errs:   map[string]error{},
 			},
-			map[string]string{},
+			detail{StatusHealthy, map[string]checkDetail{}},
 		},
 		{
 			"no_error",
@@ -120,13 +192,14 @@ func TestHealthCheck_handlerDetail(t *testing.T) {
 				"checker_2": &mockCheck{},
 			}},
 			args{
-				context.Background(),
-				map[string]error{},
-			},
-			map[string]string{
-				"checker_1": "OK",
-				"checker_2": "OK",
+				ctx:    context.Background(),
+				status: StatusHealthy,
+				errs:   map[string]error{},
 			},
+			detail{StatusHealthy, map[string]checkDetail{
+				"checker_1": {Status: StatusHealthy, LastDuration: "0s"},
+				"checker_2": {Status: StatusHealthy, LastDuration: "0s"},
+			}},
 		},
 		{
 			"2_errors_2_success",
@@ -137,18 +210,38 @@ func TestHealthCheck_handlerDetail(t *testing.T) {
 				"checker_4": &mockCheck{},
 			}},
 			args{
-				context.Background(),
-				map[string]error{
+				ctx:    context.Background(),
+				status: StatusUnhealthy,
+				errs: map[string]error{
 					"checker_2": errors.New("checker_2 failed"),
 					"checker_4": errors.New("checker_4 failed"),
 				},
 			},
-			map[string]string{
-				"checker_1": "OK",
-				"checker_2": "checker_2 failed",
-				"checker_3": "OK",
-				"checker_4": "checker_4 failed",
+			detail{StatusUnhealthy, map[string]checkDetail{
+				"checker_1": {Status: StatusHealthy, LastDuration: "0s"},
+				"checker_2": {Status: StatusUnhealthy, Error: "checker_2 failed", LastDuration: "0s"},
+				"checker_3": {Status: StatusHealthy, LastDuration: "0s"},
+				"checker_4": {Status: StatusUnhealthy, Error: "checker_4 failed", LastDuration: "0s"},
+			}},
+		},
+		{
+			"tagged",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{tags: []string{"liveness"}},
+				"checker_2": &mockCheck{tags: []string{"readiness"}},
+				"checker_3": &mockCheck{},
+			}},
+			args{
+				ctx:    context.Background(),
+				status: StatusDegraded,
+				errs: map[string]error{
+					"checker_2": errors.New("checker_2 failed"),
+				},
+				tags: []string{"readiness"},
 			},
+			detail{StatusDegraded, map[string]checkDetail{
+				"checker_2": {Status: StatusUnhealthy, Error: "checker_2 failed", LastDuration: "0s"},
+			}},
 		},
 	}
 	for _, tt := range tests {
@@ -157,8 +250,8 @@ func TestHealthCheck_handlerDetail(t *testing.T) {
 			h := &HealthCheck{
 				checkers: tt.fields.checkers,
 			}
-			h.handlerDetail(tt.args.ctx, w, tt.args.errs)
-			got := make(map[string]string)
+			h.handlerDetail(tt.args.ctx, w, tt.args.status, tt.args.errs, tt.args.tags)
+			var got detail
 			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
 				t.Errorf("handlerDetail() response is not JSON %v", w.Body.String())
 			}
@@ -168,3 +261,324 @@ func TestHealthCheck_handlerDetail(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthCheck_Handle(t *testing.T) {
+	type fields struct {
+		checkers map[string]checker
+	}
+	type args struct {
+		tags []string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   int
+	}{
+		{
+			"all",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{tags: []string{"liveness"}},
+				"checker_2": &mockCheck{tags: []string{"readiness"}, err: errors.New("checker_2 failed")},
+			}},
+			args{},
+			http.StatusServiceUnavailable,
+		},
+		{
+			"liveness",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{tags: []string{"liveness"}},
+				"checker_2": &mockCheck{tags: []string{"readiness"}, err: errors.New("checker_2 failed")},
+			}},
+			args{[]string{"liveness"}},
+			http.StatusOK,
+		},
+		{
+			"readiness",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{tags: []string{"liveness"}},
+				"checker_2": &mockCheck{tags: []string{"readiness"}, err: errors.New("checker_2 failed")},
+			}},
+			args{[]string{"readiness"}},
+			http.StatusServiceUnavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serveMux := http.NewServeMux()
+			h := &HealthCheck{
+				checkers: tt.fields.checkers,
+			}
+			h.Handle(serveMux, "/probe", tt.args.tags...)
+			w := httptest.NewRecorder()
+			serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/probe", nil))
+			if w.Code != tt.want {
+				t.Errorf("Handle() code = %v, want %v", w.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_HandleStartup(t *testing.T) {
+	var (
+		mutex   sync.Mutex
+		failing = true
+	)
+	h := New(http.NewServeMux(), "/healthcheck")
+	h.Register("database", func(_ context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if failing {
+			return errors.New("database failed")
+		}
+		return nil
+	}, time.Second, WithTags("startup"))
+	h.Register("other", func(_ context.Context) error { return errors.New("other failed") }, time.Second)
+	serveMux := http.NewServeMux()
+	h.HandleStartup(serveMux, "/startup", "startup")
+	probe := func() int {
+		w := httptest.NewRecorder()
+		serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/startup", nil))
+		return w.Code
+	}
+	setFailing := func(f bool) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		failing = f
+	}
+
+	if got := probe(); got != http.StatusServiceUnavailable {
+		t.Errorf("HandleStartup() code = %v, want %v", got, http.StatusServiceUnavailable)
+	}
+	setFailing(false)
+	if got := probe(); got != http.StatusOK {
+		t.Errorf("HandleStartup() code = %v, want %v", got, http.StatusOK)
+	}
+	// Latched, failures after startup don't matter.
+	setFailing(true)
+	if got := probe(); got != http.StatusOK {
+		t.Errorf("HandleStartup() latched code = %v, want %v", got, http.StatusOK)
+	}
+	if runs := h.tagged([]string{"startup"})["database"].result().runs; runs != 2 {
+		t.Errorf("HandleStartup() runs = %v, want %v", runs, 2)
+	}
+}
+
+// The startup probe does not latch before registration of the checks.
+func TestHealthCheck_HandleStartup_beforeRegister(t *testing.T) {
+	h := New(http.NewServeMux(), "/healthcheck")
+	serveMux := http.NewServeMux()
+	h.HandleStartup(serveMux, "/startup", "db")
+	probe := func() int {
+		w := httptest.NewRecorder()
+		serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/startup", nil))
+		return w.Code
+	}
+	if got := probe(); got != http.StatusServiceUnavailable {
+		t.Errorf("HandleStartup() code = %v, want %v", got, http.StatusServiceUnavailable)
+	}
+	h.Register("db", func(_ context.Context) error { return errors.New("db failed") }, time.Second, WithTags("db"))
+	if got := probe(); got != http.StatusServiceUnavailable {
+		t.Errorf("HandleStartup() code = %v, want %v", got, http.StatusServiceUnavailable)
+	}
+	h.Register("db", func(_ context.Context) error { return nil }, time.Second, WithTags("db"))
+	if got := probe(); got != http.StatusOK {
+		t.Errorf("HandleStartup() code = %v, want %v", got, http.StatusOK)
+	}
+}
+
+func Test_newCheckDetail(t *testing.T) {
+	testErr := errors.New("newCheckDetail error")
+	now := time.Now()
+	type args struct {
+		r   result
+		err error
+	}
+	tests := []struct {
+		name string
+		args args
+		want checkDetail
+	}{
+		{
+			"never_checked",
+			args{result{}, nil},
+			checkDetail{Status: StatusHealthy, LastDuration: "0s"},
+		},
+		{
+			"healthy",
+			args{
+				result{
+					successesInARow: 2,
+					runs:            3,
+					failures:        1,
+					lastRun:         now,
+					lastSuccess:     now,
+					lastFailure:     now.Add(-time.Minute),
+					lastDuration:    time.Second,
+				},
+				nil,
+			},
+			checkDetail{
+				Status:          StatusHealthy,
+				LastRun:         &now,
+				Age:             "0s",
+				LastSuccess:     &now,
+				LastFailure:     timeOrNil(now.Add(-time.Minute)),
+				LastDuration:    "1s",
+				Runs:            3,
+				Failures:        1,
+				SuccessesInARow: 2,
+			},
+		},
+		{
+			"unknown",
+			args{result{err: errNeverChecked}, errNeverChecked},
+			checkDetail{Status: StatusUnknown, Error: errNeverChecked.Error(), LastDuration: "0s"},
+		},
+		{
+			"background",
+			args{result{interval: time.Minute}, nil},
+			checkDetail{Status: StatusHealthy, LastDuration: "0s", Interval: "1m0s"},
+		},
+		{
+			"unhealthy",
+			args{
+				result{
+					err:          testErr,
+					errorsInARow: 1,
+					runs:         1,
+					failures:     1,
+					lastRun:      now,
+					lastFailure:  now,
+					lastDuration: time.Millisecond,
+				},
+				testErr,
+			},
+			checkDetail{
+				Status:       StatusUnhealthy,
+				Error:        testErr.Error(),
+				LastRun:      &now,
+				Age:          "0s",
+				LastFailure:  &now,
+				LastDuration: "1ms",
+				Runs:         1,
+				Failures:     1,
+				ErrorsInARow: 1,
+			},
+		},
+		{
+			"panicked",
+			args{
+				result{
+					err:          testErr,
+					errorsInARow: 1,
+					runs:         1,
+					failures:     1,
+					panics:       1,
+				},
+				testErr,
+			},
+			checkDetail{
+				Status:       StatusUnhealthy,
+				Error:        testErr.Error(),
+				LastDuration: "0s",
+				Runs:         1,
+				Failures:     1,
+				Panics:       1,
+				ErrorsInARow: 1,
+			},
+		},
+		{
+			"abandoned",
+			args{result{abandoned: 2}, nil},
+			checkDetail{Status: StatusHealthy, LastDuration: "0s", Abandoned: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newCheckDetail(tt.args.r, tt.args.err, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newCheckDetail() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_handlerHealthJSON(t *testing.T) {
+	now := time.Date(2020, time.May, 1, 10, 0, 0, 0, time.UTC)
+	type fields struct {
+		checkers map[string]checker
+	}
+	type args struct {
+		status Status
+		errs   map[string]error
+		tags   []string
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+		want   healthJSON
+	}{
+		{
+			"empty",
+			fields{map[string]checker{}},
+			args{
+				status: StatusHealthy,
+				errs:   map[string]error{},
+			},
+			healthJSON{"pass", map[string][]healthJSONCheck{}},
+		},
+		{
+			"mix",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{lastRun: now, lastDuration: 1500 * time.Microsecond},
+				"checker_2": &mockCheck{severity: NonCritical, component: "datastore"},
+				"checker_3": &mockCheck{tags: []string{"liveness"}},
+			}},
+			args{
+				status: StatusDegraded,
+				errs: map[string]error{
+					"checker_2": errors.New("checker_2 failed"),
+				},
+			},
+			healthJSON{"warn", map[string][]healthJSONCheck{
+				"checker_1": {{ComponentType: "component", ObservedValue: 1.5, ObservedUnit: "ms", Status: "pass", Time: &now}},
+				"checker_2": {{ComponentType: "datastore", ObservedUnit: "ms", Status: "warn", Output: "checker_2 failed"}},
+				"checker_3": {{ComponentType: "component", ObservedUnit: "ms", Status: "pass"}},
+			}},
+		},
+		{
+			"tagged_fail",
+			fields{map[string]checker{
+				"checker_1": &mockCheck{tags: []string{"liveness"}},
+				"checker_2": &mockCheck{},
+			}},
+			args{
+				status: StatusUnhealthy,
+				errs: map[string]error{
+					"checker_1": errors.New("checker_1 failed"),
+				},
+				tags: []string{"liveness"},
+			},
+			healthJSON{"fail", map[string][]healthJSONCheck{
+				"checker_1": {{ComponentType: "component", ObservedUnit: "ms", Status: "fail", Output: "checker_1 failed"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h := &HealthCheck{
+				checkers: tt.fields.checkers,
+			}
+			h.handlerHealthJSON(context.Background(), w, tt.args.status, tt.args.errs, tt.args.tags)
+			var got healthJSON
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Errorf("handlerHealthJSON() response is not JSON %v", w.Body.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("handlerHealthJSON() body = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,46 +3,130 @@ package healthcheck
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
-	"reflect"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
 
+// waitHealthyInterval is the interval of running synchronous checks while waiting for them to be healthy.
+const waitHealthyInterval = 500 * time.Millisecond
+
+// errNotRegistered is the error of a check that is waited for but not registered.
+var errNotRegistered = errors.New("not registered")
+
 // A checker is all that HealthCheck needs to know about the check.
 type checker interface {
 	check(ctx context.Context) error
 	run(ctx context.Context)
+	state() error
+	result() result
 	isInBackground() bool
-	ticker() *time.Ticker
+	isCritical() bool
+	componentType() string
+	hasTag(tags []string) bool
+	nextInterval() time.Duration
+	initialDelay() time.Duration
+	inGracePeriod(now time.Time) bool
+	refresh(now time.Time)
+	schedule(first time.Time)
+}
+
+// A Status is the overall status of checks.
+type Status string
+
+// Statuses
+const (
+	// StatusHealthy shows all checks pass.
+	StatusHealthy Status = "healthy"
+	// StatusDegraded shows only NonCritical checks fail.
+	StatusDegraded Status = "degraded"
+	// StatusUnhealthy shows at least one Critical check fails.
+	StatusUnhealthy Status = "unhealthy"
+	// StatusUnknown shows at least one Critical check is not checked yet, and none fails.
+	StatusUnknown Status = "unknown"
+)
+
+// A StatusChange is an event of a change of the threshold adjusted status of a check or the overall status.
+type StatusChange struct {
+	// Name of the check. It is empty for the overall status.
+	Name string
+	// Old status.
+	Old Status
+	// New status.
+	New Status
+	// Err is the error of the check if it is unhealthy. It is nil for the overall status.
+	Err error
+	// Time of the change.
+	Time time.Time
 }
 
 // A HealthCheck holds all details of checkers and manage their executions.
 type HealthCheck struct {
 	mutex            sync.RWMutex
 	checkers         map[string]checker
-	backgrounds      []backgroundChecker
 	backgroundCancel context.CancelFunc
+	reload           chan struct{}
+	workers          int
+	listeners        []func(StatusChange)
+	waiters          map[chan struct{}]struct{}
+	overall          Status
+	statusMutex      sync.Mutex
+	maxConcurrency   int
+	created          time.Time
+	gracePeriod      time.Duration
+	graceEnded       bool
+	graceMutex       sync.Mutex
 }
 
-// A backgroundChecker holds a background check and its ticker.
-type backgroundChecker struct {
-	checker checker
-	ticker  *time.Ticker
-}
+// An Option is a modifier of a HealthCheck. It can be passed while creating a HealthCheck to customize it.
+type Option func(h *HealthCheck)
 
 // New creates a new HealthCheck.
 // 	serve			ServeMux to register handler. If not sure, pass http.DefaultServeMux.
 // 	handlerPattern	patten for handler (e.g. "/healthcheck").
-func New(serve *http.ServeMux, handlerPattern string) *HealthCheck {
+// 	opts			HealthCheck options e.g. max concurrency.
+func New(serve *http.ServeMux, handlerPattern string, opts ...Option) *HealthCheck {
 	h := &HealthCheck{
-		checkers:    make(map[string]checker),
-		backgrounds: make([]backgroundChecker, 0),
+		checkers: make(map[string]checker),
+		created:  time.Now(),
+	}
+	for i := range opts {
+		opts[i](h)
 	}
 	serve.HandleFunc(handlerPattern, h.handler)
 	return h
 }
 
+// WithMaxConcurrency limits the number of synchronous checks that run concurrently per request.
+// By default, there is no limit.
+// Returns an Option that can be passed during the HealthCheck creation.
+func WithMaxConcurrency(n int) Option {
+	return func(h *HealthCheck) {
+		h.maxConcurrency = n
+	}
+}
+
+// WithWorkers sets the number of goroutines that run background checks. Default is 4.
+// Returns an Option that can be passed during the HealthCheck creation.
+func WithWorkers(n int) Option {
+	return func(h *HealthCheck) {
+		h.workers = n
+	}
+}
+
+// WithGracePeriod ignores failures of checks in the overall status for a period after the HealthCheck creation.
+// Failures are still reported in the detail. The grace period ends early once every check has succeeded once.
+// Returns an Option that can be passed during the HealthCheck creation.
+func WithGracePeriod(period time.Duration) Option {
+	return func(h *HealthCheck) {
+		h.gracePeriod = period
+	}
+}
+
 // Register will register a Checker for a HealthCheck.
 // Params:
 // 	name	Name of the check. Will be used in the detailed output.
@@ -50,22 +134,77 @@ func New(serve *http.ServeMux, handlerPattern string) *HealthCheck {
 // 	timeout	Timeout of the check execution.
 // 	opts	Checker options e.g. run in background.
 func (h *HealthCheck) Register(name string, c Checker, timeout time.Duration, opts ...CheckOption) {
+	check := newCheck(c, timeout, opts...)
+	check.onStatusChange = func(old, new Status, err error, t time.Time) {
+		h.statusChanged(StatusChange{name, old, new, err, t})
+	}
 	h.mutex.Lock()
-	defer h.mutex.Unlock()
-	h.checkers[name] = newCheck(c, timeout, opts...)
+	h.checkers[name] = check
+	h.mutex.Unlock()
+	h.checkersChanged()
 }
 
-// Run executes a goroutine that runs background checkers.
-func (h *HealthCheck) Run(ctx context.Context) {
+// Unregister will remove a Checker from a HealthCheck. It is safe to call it after Run.
+// 	name	Name of the check.
+func (h *HealthCheck) Unregister(name string) {
 	h.mutex.Lock()
-	for _, c := range h.checkers {
-		if c.isInBackground() {
-			h.backgrounds = append(h.backgrounds, backgroundChecker{c, c.ticker()})
-		}
+	delete(h.checkers, name)
+	h.mutex.Unlock()
+	h.checkersChanged()
+}
+
+// checkersChanged updates the overall status and notifies the background goroutine about changes of checkers.
+func (h *HealthCheck) checkersChanged() {
+	h.statusChanged(StatusChange{Time: time.Now()})
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	if h.reload == nil {
+		return
 	}
-	if len(h.backgrounds) > 0 {
-		ctx, h.backgroundCancel = context.WithCancel(ctx)
+	select {
+	case h.reload <- struct{}{}:
+	default:
+		// A reload is already pending.
 	}
+}
+
+// OnStatusChange registers a listener that is called when the threshold adjusted status of a check
+// or the overall status changes, by background or synchronous runs.
+// Listeners are called synchronously after the check execution, keep them fast.
+func (h *HealthCheck) OnStatusChange(listener func(change StatusChange)) {
+	h.statusMutex.Lock()
+	defer h.statusMutex.Unlock()
+	h.listeners = append(h.listeners, listener)
+}
+
+// Handle registers a handler that only evaluates checks carrying any of the tags.
+// It is useful to separate probes, e.g. liveness and readiness, on different patterns.
+// 	serve	ServeMux to register handler.
+// 	pattern	patten for handler (e.g. "/readiness").
+// 	tags	Tags of the checks. All checks are evaluated if no tag is passed.
+func (h *HealthCheck) Handle(serve *http.ServeMux, pattern string, tags ...string) {
+	serve.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		h.handle(w, r, tags...)
+	})
+}
+
+// HandleStartup registers a startup probe handler, e.g. for Kubernetes startup probes.
+// It returns 503 until every check carrying any of the tags has succeeded at least once, then it always returns 200.
+// Synchronous checks run on each request until then. It returns 503 while no check carries the tags,
+// so checks registered at runtime are waited for.
+// 	serve	ServeMux to register handler.
+// 	pattern	patten for handler (e.g. "/startup").
+// 	tags	Tags of the checks. All checks are evaluated if no tag is passed.
+func (h *HealthCheck) HandleStartup(serve *http.ServeMux, pattern string, tags ...string) {
+	serve.HandleFunc(pattern, h.startupHandler(tags))
+}
+
+// Run executes a goroutine that schedules background checkers and a pool of workers that run them.
+// Checkers registered, replaced or unregistered after Run are picked up by the scheduler.
+func (h *HealthCheck) Run(ctx context.Context) {
+	h.mutex.Lock()
+	ctx, h.backgroundCancel = context.WithCancel(ctx)
+	h.reload = make(chan struct{}, 1)
 	h.mutex.Unlock()
 	go h.runInBackground(ctx)
 }
@@ -74,46 +213,245 @@ func (h *HealthCheck) Run(ctx context.Context) {
 func (h *HealthCheck) Close() {
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
-	for i := range h.backgrounds {
-		h.backgrounds[i].ticker.Stop()
-	}
 	if h.backgroundCancel != nil {
 		h.backgroundCancel()
 	}
 }
 
-// Check will check health of all checkers.
-func (h *HealthCheck) check(ctx context.Context) map[string]error {
-	var err error
+// tagged returns checkers carrying any of the tags, or all checkers if no tag is passed.
+func (h *HealthCheck) tagged(tags []string) map[string]checker {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	checkers := make(map[string]checker, len(h.checkers))
+	for name, c := range h.checkers {
+		if c.hasTag(tags) {
+			checkers[name] = c
+		}
+	}
+	return checkers
+}
+
+// Check will check health of all checkers carrying any of the tags, or all checkers if no tag is passed.
+// Synchronous checkers run concurrently, limited by maxConcurrency.
+func (h *HealthCheck) check(ctx context.Context, tags ...string) map[string]error {
+	return h.checkEach(ctx, h.tagged(tags))
+}
+
+// checkEach checks health of checkers. Synchronous checkers run concurrently, limited by maxConcurrency.
+func (h *HealthCheck) checkEach(ctx context.Context, checkers map[string]checker) map[string]error {
+	var (
+		mutex     sync.Mutex
+		wg        sync.WaitGroup
+		semaphore chan struct{}
+	)
+	if h.maxConcurrency > 0 {
+		semaphore = make(chan struct{}, h.maxConcurrency)
+	}
 	errs := make(map[string]error)
-	for name, checker := range h.checkers {
-		err = checker.check(ctx)
+	record := func(name string, err error) {
 		if err != nil {
+			mutex.Lock()
 			errs[name] = err
+			mutex.Unlock()
 		}
 	}
+	for name, c := range checkers {
+		if c.isInBackground() {
+			record(name, c.check(ctx))
+			continue
+		}
+		wg.Add(1)
+		go func(name string, c checker) {
+			defer wg.Done()
+			if semaphore != nil {
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+			}
+			record(name, c.check(ctx))
+		}(name, c)
+	}
+	wg.Wait()
 	return errs
 }
 
-// runInBackground listens to background checkers tickers and run the checkers checkers.
-func (h *HealthCheck) runInBackground(ctx context.Context) {
+// status calculates the overall status from errors of checkers.
+// Errors of unregistered checkers and checkers in their grace period are ignored.
+// A failing Critical checker makes it unhealthy, an unknown Critical checker makes it unknown
+// and any other failing or unknown checker makes it degraded.
+func (h *HealthCheck) status(errs map[string]error) Status {
+	now := time.Now()
+	if h.inGracePeriod(now) {
+		return StatusHealthy
+	}
 	h.mutex.RLock()
 	defer h.mutex.RUnlock()
-	if len(h.backgrounds) == 0 {
-		return
+	status := StatusHealthy
+	for name, err := range errs {
+		checker, ok := h.checkers[name]
+		if !ok || checker.inGracePeriod(now) {
+			continue
+		}
+		switch {
+		case !checker.isCritical():
+			if status == StatusHealthy {
+				status = StatusDegraded
+			}
+		case errStatus(err) == StatusUnknown:
+			status = StatusUnknown
+		default:
+			return StatusUnhealthy
+		}
+	}
+	return status
+}
+
+// inGracePeriod shows if the HealthCheck is in its grace period at now.
+// Once the grace period ends, by time or by success of every registered check, it never starts again.
+func (h *HealthCheck) inGracePeriod(now time.Time) bool {
+	h.graceMutex.Lock()
+	defer h.graceMutex.Unlock()
+	if h.gracePeriod <= 0 || h.graceEnded {
+		return false
+	}
+	if now.Sub(h.created) >= h.gracePeriod || h.succeeded(nil) {
+		h.graceEnded = true
+		return false
+	}
+	return true
+}
+
+// succeeded shows if every checker carrying any of the tags, or every checker if no tag is passed,
+// has succeeded at least once. It is false if there is no such checker, as they may be registered later.
+func (h *HealthCheck) succeeded(tags []string) bool {
+	checkers := h.tagged(tags)
+	if len(checkers) == 0 {
+		return false
+	}
+	for _, checker := range checkers {
+		if checker.result().lastSuccess.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// currentStatus calculates the overall status from the last results of checkers without running them.
+func (h *HealthCheck) currentStatus() Status {
+	errs := make(map[string]error)
+	for name, checker := range h.tagged(nil) {
+		if err := checker.state(); err != nil {
+			errs[name] = err
+		}
+	}
+	return h.status(errs)
+}
+
+// statusChanged notifies listeners about the change of a check and the overall status if it changes too.
+// A change without Name only updates the overall status.
+func (h *HealthCheck) statusChanged(change StatusChange) {
+	var changes []StatusChange
+	if change.Name != "" {
+		changes = append(changes, change)
+	}
+	h.statusMutex.Lock()
+	overall := h.currentStatus()
+	if h.overall != "" && overall != h.overall {
+		changes = append(changes, StatusChange{Old: h.overall, New: overall, Time: change.Time})
+	}
+	h.overall = overall
+	listeners := h.listeners
+	for waiter := range h.waiters {
+		select {
+		case waiter <- struct{}{}:
+		default:
+			// The waiter is already notified.
+		}
+	}
+	h.statusMutex.Unlock()
+	for i := range changes {
+		for j := range listeners {
+			listeners[j](changes[i])
+		}
+	}
+}
+
+// A WaitError is the error of WaitHealthy when the context ends before the checks are healthy.
+type WaitError struct {
+	// Err is the error of the context.
+	Err error
+	// Checks are the last errors of the checks that are not healthy, by name.
+	Checks map[string]error
+}
+
+func (e *WaitError) Error() string {
+	names := make([]string, 0, len(e.Checks))
+	for name := range e.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s: %v", names[i], e.Checks[names[i]])
 	}
-	selects := make([]reflect.SelectCase, len(h.backgrounds)+1)
-	for i := range h.backgrounds {
-		h.backgrounds[i].checker.run(ctx)
-		selects[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(h.backgrounds[i].ticker.C)}
+	return fmt.Sprintf("%v, not healthy checks: %s", e.Err, strings.Join(names, ", "))
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// WaitHealthy blocks until the checks with the names, or all checks if no name is passed, are healthy.
+// Background checks are followed by their results and synchronous checks run periodically.
+// If the context ends first, it returns a WaitError with the last errors of the checks that are not healthy.
+// 	ctx		Context of waiting, e.g. with a timeout.
+// 	names	Names of the checks. A check that is not registered yet is not healthy.
+func (h *HealthCheck) WaitHealthy(ctx context.Context, names ...string) error {
+	waiter := make(chan struct{}, 1)
+	h.statusMutex.Lock()
+	if h.waiters == nil {
+		h.waiters = make(map[chan struct{}]struct{})
 	}
-	selects[len(h.backgrounds)] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())}
+	h.waiters[waiter] = struct{}{}
+	h.statusMutex.Unlock()
+	defer func() {
+		h.statusMutex.Lock()
+		delete(h.waiters, waiter)
+		h.statusMutex.Unlock()
+	}()
+	timer := time.NewTimer(waitHealthyInterval)
+	defer timer.Stop()
 	for {
-		chosen, _, ok := reflect.Select(selects)
-		if !ok {
-			// Context canceled
-			return
+		errs := h.unhealthy(ctx, names)
+		if len(errs) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return &WaitError{Err: ctx.Err(), Checks: errs}
+		case <-waiter:
+		case <-timer.C:
+			timer.Reset(waitHealthyInterval)
+		}
+	}
+}
+
+// unhealthy checks health of the checkers with the names, or all checkers if no name is passed,
+// and returns errors of the ones that are not healthy.
+func (h *HealthCheck) unhealthy(ctx context.Context, names []string) map[string]error {
+	checkers := h.tagged(nil)
+	errs := make(map[string]error)
+	if len(names) > 0 {
+		selected := make(map[string]checker, len(names))
+		for _, name := range names {
+			if c, ok := checkers[name]; ok {
+				selected[name] = c
+			} else {
+				errs[name] = errNotRegistered
+			}
 		}
-		h.backgrounds[chosen].checker.run(ctx)
+		checkers = selected
+	}
+	for name, err := range h.checkEach(ctx, checkers) {
+		errs[name] = err
 	}
+	return errs
 }
@@ -4,7 +4,9 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 )
@@ -17,15 +19,19 @@ func TestNew(t *testing.T) {
 		{
 			"simple",
 			&HealthCheck{
-				checkers:    make(map[string]checker),
-				backgrounds: make([]backgroundChecker, 0),
+				checkers: make(map[string]checker),
 			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			serveMux := http.NewServeMux()
-			if got := New(serveMux, "/healthcheck"); !reflect.DeepEqual(got, tt.want) {
+			got := New(serveMux, "/healthcheck")
+			if got.created.IsZero() {
+				t.Error("New().created is zero")
+			}
+			got.created = time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("New() = %v, want %v", got, tt.want)
 			}
 		})
@@ -114,6 +120,7 @@ func TestHealthCheck_Register(t *testing.T) {
 }
 
 func TestHealthCheck_Run(t *testing.T) {
+	testErr := errors.New("HealthCheck.Run error")
 	type fields struct {
 		checkers map[string]checker
 	}
@@ -136,8 +143,8 @@ func TestHealthCheck_Run(t *testing.T) {
 			"2_fronts",
 			fields{
 				map[string]checker{
-					"front_1": &mockCheck{},
-					"front_2": &mockCheck{},
+					"front_1": &mockCheck{runErr: testErr},
+					"front_2": &mockCheck{runErr: testErr},
 				},
 			},
 			args{context.Background()},
@@ -147,10 +154,10 @@ func TestHealthCheck_Run(t *testing.T) {
 			"mix",
 			fields{
 				map[string]checker{
-					"front_1":      &mockCheck{},
-					"front_2":      &mockCheck{},
-					"background_1": &mockCheck{interval: time.Minute},
-					"background_2": &mockCheck{interval: time.Hour},
+					"front_1":      &mockCheck{runErr: testErr},
+					"front_2":      &mockCheck{runErr: testErr},
+					"background_1": &mockCheck{interval: time.Minute, runErr: testErr},
+					"background_2": &mockCheck{interval: time.Hour, runErr: testErr},
 				},
 			},
 			args{context.Background()},
@@ -161,28 +168,23 @@ func TestHealthCheck_Run(t *testing.T) {
 		},
 	}
 	for _, tt := range tests {
-		backgroundMap := make(map[checker]string)
-		for _, b := range tt.wantBackgrounds {
-			backgroundMap[tt.fields.checkers[b]] = b
-		}
 		t.Run(tt.name, func(t *testing.T) {
 			h := &HealthCheck{
 				checkers: tt.fields.checkers,
 			}
 			h.Run(tt.args.ctx)
-			if len(tt.wantBackgrounds) != len(h.backgrounds) {
-				t.Errorf("Run() len(backgrounds) = %v, want %v", len(h.backgrounds), len(tt.wantBackgrounds))
+			if h.backgroundCancel == nil {
+				t.Error("Run() backgroundCancel expected, got nil")
 			}
-			if len(tt.wantBackgrounds) > 0 {
-				if h.backgroundCancel == nil {
-					t.Error("Run() backgroundCancel expected, got nil")
-				}
+			time.Sleep(10 * time.Millisecond)
+			h.Close()
+			backgrounds := make(map[string]bool)
+			for _, name := range tt.wantBackgrounds {
+				backgrounds[name] = true
 			}
-			for i := range h.backgrounds {
-				bc := h.backgrounds[i].checker
-				ec := tt.fields.checkers[backgroundMap[bc]]
-				if !reflect.DeepEqual(bc, ec) {
-					t.Errorf("Run() backgrounds[%v] = %v, want %v", backgroundMap[bc], bc, ec)
+			for name, c := range tt.fields.checkers {
+				if ran := c.state() != nil; ran != backgrounds[name] {
+					t.Errorf("Run() %v ran = %v, want %v", name, ran, backgrounds[name])
 				}
 			}
 		})
@@ -190,31 +192,16 @@ func TestHealthCheck_Run(t *testing.T) {
 }
 
 func TestHealthCheck_Close(t *testing.T) {
-	type fields struct {
-		backgrounds []backgroundChecker
-	}
 	tests := []struct {
 		name                 string
-		fields               fields
 		withBackgroundCancel bool
 	}{
 		{
-			"no_background_checker",
-			fields{},
+			"not_running",
 			false,
 		},
 		{
-			"2_background_checker",
-			fields{
-				[]backgroundChecker{
-					{
-						ticker: time.NewTicker(time.Nanosecond),
-					},
-					{
-						ticker: time.NewTicker(time.Nanosecond),
-					},
-				},
-			},
+			"running",
 			true,
 		},
 	}
@@ -230,27 +217,12 @@ func TestHealthCheck_Close(t *testing.T) {
 				}
 			}
 			h := &HealthCheck{
-				backgrounds:      tt.fields.backgrounds,
 				backgroundCancel: backgroundCancel,
 			}
-			time.Sleep(2 * time.Millisecond)
 			h.Close()
 			if backgroundCancelled != tt.withBackgroundCancel {
 				t.Errorf("Close() backgroundCancel got = %v, want %v", backgroundCancelled, tt.withBackgroundCancel)
 			}
-			for i := range h.backgrounds {
-				<-h.backgrounds[i].ticker.C
-			}
-			time.Sleep(2 * time.Millisecond)
-			for i := range h.backgrounds {
-				ticker := h.backgrounds[i].ticker
-				select {
-				case <-ticker.C:
-					t.Error("Close() background checkers ticker is not stopped")
-				default:
-					// OK
-				}
-			}
 		})
 	}
 }
@@ -261,7 +233,8 @@ func TestHealthCheck_check(t *testing.T) {
 		checkers map[string]checker
 	}
 	type args struct {
-		ctx context.Context
+		ctx  context.Context
+		tags []string
 	}
 	tests := []struct {
 		name   string
@@ -274,7 +247,7 @@ func TestHealthCheck_check(t *testing.T) {
 			fields{
 				checkers: map[string]checker{},
 			},
-			args{context.Background()},
+			args{ctx: context.Background()},
 			map[string]error{},
 		},
 		{
@@ -286,94 +259,537 @@ func TestHealthCheck_check(t *testing.T) {
 					"checker_3": &mockCheck{err: testErr},
 				},
 			},
-			args{context.Background()},
+			args{ctx: context.Background()},
 			map[string]error{
 				"checker_1": testErr,
 				"checker_3": testErr,
 			},
 		},
+		{
+			"tagged",
+			fields{
+				checkers: map[string]checker{
+					"checker_1": &mockCheck{err: testErr, tags: []string{"liveness"}},
+					"checker_2": &mockCheck{err: testErr, tags: []string{"readiness"}},
+					"checker_3": &mockCheck{err: testErr},
+				},
+			},
+			args{context.Background(), []string{"readiness"}},
+			map[string]error{
+				"checker_2": testErr,
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			h := &HealthCheck{
 				checkers: tt.fields.checkers,
 			}
-			if got := h.check(tt.args.ctx); !reflect.DeepEqual(got, tt.want) {
+			if got := h.check(tt.args.ctx, tt.args.tags...); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("check() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-// Not checking if select part is actually working as we expected.
-func TestHealthCheck_runInBackground(t *testing.T) {
-	testErr := errors.New("HealthCheck.runInBackground error")
-	type fields struct {
-		backgrounds []backgroundChecker
+func TestHealthCheck_status(t *testing.T) {
+	testErr := errors.New("HealthCheck.status error")
+	checkers := map[string]checker{
+		"critical":     &mockCheck{},
+		"non_critical": &mockCheck{severity: NonCritical},
+		"grace":        &mockCheck{grace: true},
 	}
-	type args struct {
-		ctx context.Context
+	tests := []struct {
+		name string
+		errs map[string]error
+		want Status
+	}{
+		{
+			"healthy",
+			map[string]error{},
+			StatusHealthy,
+		},
+		{
+			"degraded",
+			map[string]error{"non_critical": testErr},
+			StatusDegraded,
+		},
+		{
+			"unhealthy",
+			map[string]error{"critical": testErr},
+			StatusUnhealthy,
+		},
+		{
+			"both",
+			map[string]error{"critical": testErr, "non_critical": testErr},
+			StatusUnhealthy,
+		},
+		{
+			"unknown",
+			map[string]error{"critical": errNeverChecked, "non_critical": testErr},
+			StatusUnknown,
+		},
+		{
+			"grace",
+			map[string]error{"grace": testErr, "non_critical": testErr},
+			StatusDegraded,
+		},
+		{
+			"unknown_non_critical",
+			map[string]error{"non_critical": errNeverChecked},
+			StatusDegraded,
+		},
 	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HealthCheck{
+				checkers: checkers,
+			}
+			if got := h.status(tt.errs); got != tt.want {
+				t.Errorf("status() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthCheck_inGracePeriod(t *testing.T) {
+	now := time.Now()
 	tests := []struct {
-		name   string
-		fields fields
-		args   args
+		name        string
+		checkers    map[string]checker
+		gracePeriod time.Duration
+		created     time.Time
+		want        bool
 	}{
 		{
-			"empty",
-			fields{},
-			args{context.Background()},
+			"disabled",
+			map[string]checker{"checker": &mockCheck{}},
+			0,
+			now,
+			false,
 		},
 		{
-			"2_backgrounds",
-			fields{[]backgroundChecker{
-				{
-					&mockCheck{runErr: testErr},
-					time.NewTicker(time.Millisecond),
-				},
-				{
-					&mockCheck{runErr: testErr},
-					time.NewTicker(time.Millisecond),
-				},
-			}},
-			args{context.Background()},
+			"in_grace_period",
+			map[string]checker{"checker": &mockCheck{}},
+			time.Minute,
+			now,
+			true,
+		},
+		{
+			"expired",
+			map[string]checker{"checker": &mockCheck{}},
+			time.Minute,
+			now.Add(-time.Minute),
+			false,
+		},
+		{
+			"no_checker",
+			map[string]checker{},
+			time.Minute,
+			now,
+			true,
+		},
+		{
+			"all_succeeded",
+			map[string]checker{
+				"checker_1": &mockCheck{lastSuccess: now},
+				"checker_2": &mockCheck{lastSuccess: now},
+			},
+			time.Minute,
+			now,
+			false,
+		},
+		{
+			"some_succeeded",
+			map[string]checker{
+				"checker_1": &mockCheck{lastSuccess: now},
+				"checker_2": &mockCheck{},
+			},
+			time.Minute,
+			now,
+			true,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			h := &HealthCheck{
-				backgrounds: tt.fields.backgrounds,
+				checkers:    tt.checkers,
+				gracePeriod: tt.gracePeriod,
+				created:     tt.created,
 			}
-			ctx, cancel := context.WithTimeout(tt.args.ctx, 10*time.Millisecond)
-			defer cancel()
-			h.runInBackground(ctx)
-			for i := range tt.fields.backgrounds {
-				if err := tt.fields.backgrounds[i].checker.(*mockCheck).err; err != testErr {
-					t.Errorf("runInBackground() checker.err = %v, want %v", err, testErr)
-				}
+			if got := h.inGracePeriod(now); got != tt.want {
+				t.Errorf("inGracePeriod() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Once the grace period ends, it never starts again.
+func TestHealthCheck_inGracePeriod_ended(t *testing.T) {
+	now := time.Now()
+	h := New(http.NewServeMux(), "/healthcheck", WithGracePeriod(time.Minute))
+	h.Register("checker", func(_ context.Context) error { return nil }, time.Second)
+	if !h.inGracePeriod(now) {
+		t.Error("inGracePeriod() = false, want true")
+	}
+	if got := h.status(h.check(context.Background())); got != StatusHealthy {
+		t.Errorf("status() = %v, want %v", got, StatusHealthy)
+	}
+	h.Register("failing", func(_ context.Context) error { return errors.New("failing") }, time.Second)
+	if h.inGracePeriod(now) {
+		t.Error("inGracePeriod() = true, want false")
+	}
+}
+
+// A probe before registration of checks does not end the grace period.
+func TestHealthCheck_inGracePeriod_beforeRegister(t *testing.T) {
+	serveMux := http.NewServeMux()
+	h := New(serveMux, "/healthcheck", WithGracePeriod(time.Hour))
+	probe := func() int {
+		w := httptest.NewRecorder()
+		serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthcheck", nil))
+		return w.Code
+	}
+	if got := probe(); got != http.StatusOK {
+		t.Errorf("handler() code = %v, want %v", got, http.StatusOK)
+	}
+	h.Register("failing", func(_ context.Context) error { return errors.New("failing") }, time.Second)
+	if got := probe(); got != http.StatusOK {
+		t.Errorf("handler() code in grace period = %v, want %v", got, http.StatusOK)
+	}
+}
+
+func TestWithGracePeriod(t *testing.T) {
+	h := &HealthCheck{}
+	WithGracePeriod(time.Minute)(h)
+	if h.gracePeriod != time.Minute {
+		t.Errorf("WithGracePeriod().gracePeriod = %v, want %v", h.gracePeriod, time.Minute)
+	}
+}
+
+func TestHealthCheck_OnStatusChange(t *testing.T) {
+	testErr := errors.New("HealthCheck.OnStatusChange error")
+	var (
+		failing bool
+		got     []StatusChange
+	)
+	h := New(http.NewServeMux(), "/healthcheck")
+	h.Register("critical", func(_ context.Context) error { return nil }, time.Second)
+	h.Register("non_critical", func(_ context.Context) error {
+		if failing {
+			return testErr
+		}
+		return nil
+	}, time.Second, WithSeverity(NonCritical))
+	h.check(context.Background())
+	h.OnStatusChange(func(change StatusChange) {
+		change.Time = time.Time{}
+		got = append(got, change)
+	})
+	h.check(context.Background())
+	failing = true
+	h.check(context.Background())
+	h.check(context.Background())
+	failing = false
+	h.check(context.Background())
+
+	want := map[string][]StatusChange{
+		"critical": nil,
+		"non_critical": {
+			{Name: "non_critical", Old: StatusHealthy, New: StatusUnhealthy, Err: testErr},
+			{Name: "non_critical", Old: StatusUnhealthy, New: StatusHealthy},
+		},
+		"": {
+			{Old: StatusHealthy, New: StatusDegraded},
+			{Old: StatusDegraded, New: StatusHealthy},
+		},
+	}
+	gotByName := make(map[string][]StatusChange)
+	for i := range got {
+		gotByName[got[i].Name] = append(gotByName[got[i].Name], got[i])
+	}
+	for name := range want {
+		if !reflect.DeepEqual(gotByName[name], want[name]) {
+			t.Errorf("OnStatusChange() changes of %q = %v, want %v", name, gotByName[name], want[name])
+		}
+	}
+}
+
+// A listener can run the checks again without a deadlock.
+func TestHealthCheck_OnStatusChange_reentrant(t *testing.T) {
+	h := New(http.NewServeMux(), "/healthcheck")
+	h.Register("checker", func(_ context.Context) error { return nil }, time.Second)
+	var reentered bool
+	h.OnStatusChange(func(change StatusChange) {
+		if change.Name == "checker" && !reentered {
+			reentered = true
+			h.check(context.Background())
+		}
+	})
+	done := make(chan struct{})
+	go func() {
+		h.check(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("OnStatusChange() listener that runs the check again is blocked")
+	}
+	if !reentered {
+		t.Error("OnStatusChange() listener is not called")
+	}
+}
+
+func TestWithMaxConcurrency(t *testing.T) {
+	h := &HealthCheck{}
+	opt := WithMaxConcurrency(3)
+	opt(h)
+	if h.maxConcurrency != 3 {
+		t.Errorf("WithMaxConcurrency().maxConcurrency = %v, want %v", h.maxConcurrency, 3)
+	}
+}
+
+func TestHealthCheck_check_concurrency(t *testing.T) {
+	sleep := 50 * time.Millisecond
+	checkerFunc := func(_ context.Context) error {
+		time.Sleep(sleep)
+		return nil
+	}
+	tests := []struct {
+		name           string
+		maxConcurrency int
+		minDuration    time.Duration
+		maxDuration    time.Duration
+	}{
+		{
+			"unlimited",
+			0,
+			sleep,
+			3 * sleep,
+		},
+		{
+			"limited",
+			1,
+			3 * sleep,
+			time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(http.NewServeMux(), "/healthcheck", WithMaxConcurrency(tt.maxConcurrency))
+			h.Register("checker_1", checkerFunc, time.Second)
+			h.Register("checker_2", checkerFunc, time.Second)
+			h.Register("checker_3", checkerFunc, time.Second)
+			start := time.Now()
+			if errs := h.check(context.Background()); len(errs) != 0 {
+				t.Errorf("check() = %v, want no errors", errs)
+			}
+			if d := time.Since(start); d < tt.minDuration || d >= tt.maxDuration {
+				t.Errorf("check() took %v, want in [%v, %v)", d, tt.minDuration, tt.maxDuration)
 			}
 		})
 	}
 }
 
+func TestHealthCheck_Unregister(t *testing.T) {
+	testErr := errors.New("HealthCheck.Unregister error")
+	h := &HealthCheck{
+		checkers: map[string]checker{
+			"checker_1": &mockCheck{},
+			"checker_2": &mockCheck{err: testErr},
+		},
+	}
+	h.Unregister("checker_2")
+	h.Unregister("not_registered")
+	if _, ok := h.checkers["checker_2"]; ok || len(h.checkers) != 1 {
+		t.Errorf("Unregister() checkers = %v, want only checker_1", h.checkers)
+	}
+	if h.overall != StatusHealthy {
+		t.Errorf("Unregister() overall = %v, want %v", h.overall, StatusHealthy)
+	}
+}
+
+// A background check registered after Run runs, and it stops running after Unregister.
+func TestHealthCheck_Run_live(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		runs  int
+	)
+	runsNow := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return runs
+	}
+	h := New(http.NewServeMux(), "/healthcheck")
+	h.Run(context.Background())
+	defer h.Close()
+	h.Register("background", func(_ context.Context) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		runs++
+		return nil
+	}, time.Second, InBackground(time.Millisecond))
+	time.Sleep(20 * time.Millisecond)
+	if runsNow() == 0 {
+		t.Error("Run() registered background checker did not run")
+	}
+	h.Unregister("background")
+	time.Sleep(5 * time.Millisecond)
+	stopped := runsNow()
+	time.Sleep(20 * time.Millisecond)
+	if got := runsNow(); got != stopped {
+		t.Errorf("Run() unregistered background checker runs = %v, want %v", got, stopped)
+	}
+}
+
 type mockCheck struct {
-	interval time.Duration
-	err      error
-	runErr   error
+	mutex        sync.Mutex
+	interval     time.Duration
+	delay        time.Duration
+	grace        bool
+	firstRun     time.Time
+	refreshes    int
+	severity     Severity
+	component    string
+	tags         []string
+	err          error
+	runErr       error
+	runs         uint64
+	failures     uint64
+	panics       uint64
+	abandoned    uint64
+	lastRun      time.Time
+	lastSuccess  time.Time
+	lastDuration time.Duration
 }
 
 func (m *mockCheck) check(_ context.Context) error {
-	return m.err
+	return m.state()
 }
 
 func (m *mockCheck) run(_ context.Context) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
 	m.err = m.runErr
 }
 
+func (m *mockCheck) state() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.err
+}
+
+func (m *mockCheck) result() result {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return result{
+		err:          m.err,
+		runs:         m.runs,
+		failures:     m.failures,
+		panics:       m.panics,
+		abandoned:    m.abandoned,
+		lastRun:      m.lastRun,
+		lastSuccess:  m.lastSuccess,
+		lastDuration: m.lastDuration,
+	}
+}
+
+func (m *mockCheck) componentType() string {
+	return (&check{component: m.component}).componentType()
+}
+
 func (m *mockCheck) isInBackground() bool {
 	return m.interval != 0
 }
 
-func (m *mockCheck) ticker() *time.Ticker {
-	return time.NewTicker(m.interval)
+func (m *mockCheck) isCritical() bool {
+	return m.severity == Critical
+}
+
+func (m *mockCheck) hasTag(tags []string) bool {
+	return (&check{tags: m.tags}).hasTag(tags)
+}
+
+func (m *mockCheck) nextInterval() time.Duration {
+	return m.interval
+}
+
+func (m *mockCheck) initialDelay() time.Duration {
+	return m.delay
+}
+
+func (m *mockCheck) inGracePeriod(_ time.Time) bool {
+	return m.grace
+}
+
+func (m *mockCheck) refresh(_ time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.refreshes++
+}
+
+func (m *mockCheck) schedule(first time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.firstRun = first
+}
+
+func TestHealthCheck_WaitHealthy(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		runs  = make(map[string]int)
+	)
+	// Checkers fail on their first execution.
+	checkerCreator := func(name string) Checker {
+		return func(_ context.Context) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			runs[name]++
+			if runs[name] == 1 {
+				return errors.New(name + " is starting")
+			}
+			return nil
+		}
+	}
+	h := New(http.NewServeMux(), "/healthcheck")
+	h.Register("background", checkerCreator("background"), time.Second, InBackground(10*time.Millisecond))
+	h.Register("synchronous", checkerCreator("synchronous"), time.Second)
+	h.Register("failing", func(_ context.Context) error { return errors.New("failing") }, time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	h.Run(ctx)
+	defer h.Close()
+
+	if err := h.WaitHealthy(ctx, "background", "synchronous"); err != nil {
+		t.Errorf("WaitHealthy() = %v, want nil", err)
+	}
+
+	waitCtx, waitCancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer waitCancel()
+	err := h.WaitHealthy(waitCtx, "background", "failing", "missing")
+	var waitErr *WaitError
+	if !errors.As(err, &waitErr) {
+		t.Fatalf("WaitHealthy() = %v, want a WaitError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitHealthy() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(waitErr.Checks) != 2 || waitErr.Checks["missing"] != errNotRegistered || waitErr.Checks["failing"] == nil {
+		t.Errorf("WaitHealthy().Checks = %v, want failing and missing", waitErr.Checks)
+	}
+}
+
+func TestWaitError_Error(t *testing.T) {
+	err := &WaitError{
+		Err: context.DeadlineExceeded,
+		Checks: map[string]error{
+			"checker_2": errors.New("checker_2 failed"),
+			"checker_1": errNotRegistered,
+		},
+	}
+	want := "context deadline exceeded, not healthy checks: checker_1: not registered, checker_2: checker_2 failed"
+	if got := err.Error(); got != want {
+		t.Errorf("WaitError.Error() = %v, want %v", got, want)
+	}
 }
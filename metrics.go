@@ -0,0 +1,128 @@
+package healthcheck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// metricsContentType is the content type of Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// A metric describes a Prometheus metric family of checks.
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(c checker, r result) float64
+}
+
+// metrics are the exposed metric families. Every family has a check label.
+var metrics = []metric{
+	{
+		"healthcheck_status",
+		"Threshold adjusted status of the check, 1 if healthy and 0 if unhealthy.",
+		"gauge",
+		func(c checker, _ result) float64 {
+			if c.state() != nil {
+				return 0
+			}
+			return 1
+		},
+	},
+	{
+		"healthcheck_errors_in_a_row",
+		"Number of consecutive errors of the check.",
+		"gauge",
+		func(_ checker, r result) float64 { return float64(r.errorsInARow) },
+	},
+	{
+		"healthcheck_last_duration_seconds",
+		"Duration of the last execution of the check in seconds.",
+		"gauge",
+		func(_ checker, r result) float64 { return r.lastDuration.Seconds() },
+	},
+	{
+		"healthcheck_last_success_timestamp_seconds",
+		"Unix timestamp of the last successful execution of the check, 0 if never succeeded.",
+		"gauge",
+		func(_ checker, r result) float64 { return unixSeconds(r.lastSuccess) },
+	},
+	{
+		"healthcheck_runs_total",
+		"Total number of executions of the check.",
+		"counter",
+		func(_ checker, r result) float64 { return float64(r.runs) },
+	},
+	{
+		"healthcheck_failures_total",
+		"Total number of failed executions of the check.",
+		"counter",
+		func(_ checker, r result) float64 { return float64(r.failures) },
+	},
+	{
+		"healthcheck_panics_total",
+		"Total number of executions of the check that panicked.",
+		"counter",
+		func(_ checker, r result) float64 { return float64(r.panics) },
+	},
+	{
+		"healthcheck_abandoned_total",
+		"Total number of executions of the check that were still running after the timeout.",
+		"counter",
+		func(_ checker, r result) float64 { return float64(r.abandoned) },
+	},
+}
+
+// HandleMetrics registers a handler that exposes checks in Prometheus text exposition format.
+// It does not run the checks, it only exposes the results of the last executions.
+// 	serve	ServeMux to register handler.
+// 	pattern	patten for handler (e.g. "/metrics").
+func (h *HealthCheck) HandleMetrics(serve *http.ServeMux, pattern string) {
+	serve.HandleFunc(pattern, h.metricsHandler)
+}
+
+// metricsHandler writes metrics of checkers in Prometheus text exposition format.
+func (h *HealthCheck) metricsHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	h.writeMetrics(w)
+}
+
+// writeMetrics writes metrics of checkers, sorted by name, in Prometheus text exposition format.
+func (h *HealthCheck) writeMetrics(w io.Writer) {
+	checkers := h.tagged(nil)
+	names := make([]string, 0, len(checkers))
+	for name := range checkers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	results := make([]result, len(names))
+	for i := range names {
+		results[i] = checkers[names[i]].result()
+	}
+	for _, m := range metrics {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, name := range names {
+			_, _ = fmt.Fprintf(w, "%s{check=\"%s\"} %v\n", m.name, escapeLabelValue(name), m.value(checkers[name], results[i]))
+		}
+	}
+}
+
+// labelValueReplacer escapes label values in Prometheus text exposition format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes backslash, double-quote and line feed of a label value.
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+// unixSeconds converts a time to Unix timestamp in seconds, 0 for zero time.
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / float64(time.Second)
+}
@@ -0,0 +1,144 @@
+package healthcheck
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck_HandleMetrics(t *testing.T) {
+	serveMux := http.NewServeMux()
+	h := &HealthCheck{
+		checkers: map[string]checker{
+			"checker_1": &mockCheck{},
+		},
+	}
+	h.HandleMetrics(serveMux, "/metrics")
+	w := httptest.NewRecorder()
+	serveMux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("HandleMetrics() code = %v, want %v", w.Code, http.StatusOK)
+	}
+	if got := w.Header().Get("Content-Type"); got != metricsContentType {
+		t.Errorf("HandleMetrics() Content-Type = %v, want %v", got, metricsContentType)
+	}
+	if w.Body.Len() == 0 {
+		t.Error("HandleMetrics() body is empty")
+	}
+}
+
+func TestHealthCheck_writeMetrics(t *testing.T) {
+	tests := []struct {
+		name     string
+		checkers map[string]checker
+		want     string
+	}{
+		{
+			"empty",
+			map[string]checker{},
+			`# HELP healthcheck_status Threshold adjusted status of the check, 1 if healthy and 0 if unhealthy.
+# TYPE healthcheck_status gauge
+# HELP healthcheck_errors_in_a_row Number of consecutive errors of the check.
+# TYPE healthcheck_errors_in_a_row gauge
+# HELP healthcheck_last_duration_seconds Duration of the last execution of the check in seconds.
+# TYPE healthcheck_last_duration_seconds gauge
+# HELP healthcheck_last_success_timestamp_seconds Unix timestamp of the last successful execution of the check, 0 if never succeeded.
+# TYPE healthcheck_last_success_timestamp_seconds gauge
+# HELP healthcheck_runs_total Total number of executions of the check.
+# TYPE healthcheck_runs_total counter
+# HELP healthcheck_failures_total Total number of failed executions of the check.
+# TYPE healthcheck_failures_total counter
+# HELP healthcheck_panics_total Total number of executions of the check that panicked.
+# TYPE healthcheck_panics_total counter
+# HELP healthcheck_abandoned_total Total number of executions of the check that were still running after the timeout.
+# TYPE healthcheck_abandoned_total counter
+`,
+		},
+		{
+			"2_checkers",
+			map[string]checker{
+				"checker_2": &mockCheck{err: errors.New("checker_2 failed"), runs: 3, failures: 3, panics: 1, abandoned: 2},
+				"checker_1": &mockCheck{
+					runs:         5,
+					failures:     1,
+					lastSuccess:  time.Unix(1588327200, 500000000),
+					lastDuration: 250 * time.Millisecond,
+				},
+			},
+			`# HELP healthcheck_status Threshold adjusted status of the check, 1 if healthy and 0 if unhealthy.
+# TYPE healthcheck_status gauge
+healthcheck_status{check="checker_1"} 1
+healthcheck_status{check="checker_2"} 0
+# HELP healthcheck_errors_in_a_row Number of consecutive errors of the check.
+# TYPE healthcheck_errors_in_a_row gauge
+healthcheck_errors_in_a_row{check="checker_1"} 0
+healthcheck_errors_in_a_row{check="checker_2"} 0
+# HELP healthcheck_last_duration_seconds Duration of the last execution of the check in seconds.
+# TYPE healthcheck_last_duration_seconds gauge
+healthcheck_last_duration_seconds{check="checker_1"} 0.25
+healthcheck_last_duration_seconds{check="checker_2"} 0
+# HELP healthcheck_last_success_timestamp_seconds Unix timestamp of the last successful execution of the check, 0 if never succeeded.
+# TYPE healthcheck_last_success_timestamp_seconds gauge
+healthcheck_last_success_timestamp_seconds{check="checker_1"} 1.5883272005e+09
+healthcheck_last_success_timestamp_seconds{check="checker_2"} 0
+# HELP healthcheck_runs_total Total number of executions of the check.
+# TYPE healthcheck_runs_total counter
+healthcheck_runs_total{check="checker_1"} 5
+healthcheck_runs_total{check="checker_2"} 3
+# HELP healthcheck_failures_total Total number of failed executions of the check.
+# TYPE healthcheck_failures_total counter
+healthcheck_failures_total{check="checker_1"} 1
+healthcheck_failures_total{check="checker_2"} 3
+# HELP healthcheck_panics_total Total number of executions of the check that panicked.
+# TYPE healthcheck_panics_total counter
+healthcheck_panics_total{check="checker_1"} 0
+healthcheck_panics_total{check="checker_2"} 1
+# HELP healthcheck_abandoned_total Total number of executions of the check that were still running after the timeout.
+# TYPE healthcheck_abandoned_total counter
+healthcheck_abandoned_total{check="checker_1"} 0
+healthcheck_abandoned_total{check="checker_2"} 2
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HealthCheck{
+				checkers: tt.checkers,
+			}
+			var b bytes.Buffer
+			h.writeMetrics(&b)
+			if got := b.String(); got != tt.want {
+				t.Errorf("writeMetrics() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_escapeLabelValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{
+			"simple",
+			"database",
+			"database",
+		},
+		{
+			"special_characters",
+			"a\\b\"c\nd",
+			`a\\b\"c\nd`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := escapeLabelValue(tt.value); got != tt.want {
+				t.Errorf("escapeLabelValue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
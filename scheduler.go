@@ -0,0 +1,243 @@
+package healthcheck
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// defaultWorkers is the default number of goroutines that run background checkers.
+const defaultWorkers = 4
+
+// A scheduledCheck is a background checker in the scheduler.
+type scheduledCheck struct {
+	name    string
+	checker checker
+	next    time.Time
+	// index in the heap. It is -1 when the check is not in the heap, e.g. it is running.
+	index int
+	// removed shows the checker is unregistered or replaced while it was not in the heap.
+	removed bool
+}
+
+// A scheduleHeap is a min-heap of scheduled checks ordered by their next execution time.
+type scheduleHeap []*scheduledCheck
+
+func (s scheduleHeap) Len() int { return len(s) }
+
+func (s scheduleHeap) Less(i, j int) bool { return s[i].next.Before(s[j].next) }
+
+func (s scheduleHeap) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *scheduleHeap) Push(x interface{}) {
+	c := x.(*scheduledCheck)
+	c.index = len(*s)
+	*s = append(*s, c)
+}
+
+func (s *scheduleHeap) Pop() interface{} {
+	old := *s
+	c := old[len(old)-1]
+	old[len(old)-1] = nil
+	c.index = -1
+	*s = old[:len(old)-1]
+	return c
+}
+
+// A scheduler decides when background checkers run. It is not safe for concurrent use.
+// A check is out of the heap from the time it is due until its execution finishes,
+// so a check never runs concurrently with itself.
+type scheduler struct {
+	heap    scheduleHeap
+	checks  map[string]*scheduledCheck
+	pending []*scheduledCheck
+	// refreshInterval is the interval of re-evaluating statuses of checks without executing them,
+	// e.g. to notify about stale results. It is the shortest interval of the checks.
+	refreshInterval time.Duration
+	refreshAt       time.Time
+}
+
+// newScheduler creates a new instance of scheduler.
+func newScheduler() *scheduler {
+	return &scheduler{
+		checks: make(map[string]*scheduledCheck),
+	}
+}
+
+// sync updates scheduled checks with the background checkers.
+// New and replaced checkers are scheduled to run after their initial delay, unregistered and replaced ones are removed.
+// It updates the refresh interval with the intervals of the checks.
+func (s *scheduler) sync(checkers map[string]checker, now time.Time) {
+	for name, sc := range s.checks {
+		if c, ok := checkers[name]; ok && c == sc.checker && c.isInBackground() {
+			continue
+		}
+		if sc.index >= 0 {
+			heap.Remove(&s.heap, sc.index)
+		}
+		sc.removed = true
+		delete(s.checks, name)
+	}
+	for name, c := range checkers {
+		if _, ok := s.checks[name]; ok || !c.isInBackground() {
+			continue
+		}
+		sc := &scheduledCheck{name: name, checker: c, next: now.Add(c.initialDelay())}
+		c.schedule(sc.next)
+		s.checks[name] = sc
+		heap.Push(&s.heap, sc)
+	}
+	s.refreshInterval = 0
+	for _, sc := range s.checks {
+		if interval := sc.checker.result().interval; interval > 0 && (s.refreshInterval == 0 || interval < s.refreshInterval) {
+			s.refreshInterval = interval
+		}
+	}
+	if s.refreshInterval > 0 && (s.refreshAt.IsZero() || now.Add(s.refreshInterval).Before(s.refreshAt)) {
+		s.refreshAt = now.Add(s.refreshInterval)
+	}
+}
+
+// refresh re-evaluates statuses of the checks if it is the time.
+func (s *scheduler) refresh(now time.Time) {
+	if s.refreshInterval <= 0 || now.Before(s.refreshAt) {
+		return
+	}
+	for _, sc := range s.checks {
+		sc.checker.refresh(now)
+	}
+	s.refreshAt = now.Add(s.refreshInterval)
+}
+
+// due moves the checks that should run at now to pending.
+func (s *scheduler) due(now time.Time) {
+	for len(s.heap) > 0 && !s.heap[0].next.After(now) {
+		s.pending = append(s.pending, heap.Pop(&s.heap).(*scheduledCheck))
+	}
+}
+
+// next returns the first pending check that is not removed, or nil.
+func (s *scheduler) next() *scheduledCheck {
+	for len(s.pending) > 0 && s.pending[0].removed {
+		s.pending = s.pending[1:]
+	}
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return s.pending[0]
+}
+
+// dispatched removes the first pending check after it is sent to a worker.
+func (s *scheduler) dispatched() {
+	s.pending = s.pending[1:]
+}
+
+// reschedule puts a check back to the heap after its execution finishes.
+// The next execution is an interval after the previous scheduled time, or now if it is already passed.
+func (s *scheduler) reschedule(sc *scheduledCheck, now time.Time) {
+	if sc.removed {
+		return
+	}
+	sc.next = sc.next.Add(sc.checker.nextInterval())
+	if sc.next.Before(now) {
+		sc.next = now
+	}
+	heap.Push(&s.heap, sc)
+}
+
+// wait returns the duration until the next scheduled check or refresh, and false if there is none.
+func (s *scheduler) wait(now time.Time) (time.Duration, bool) {
+	var (
+		d  time.Duration
+		ok bool
+	)
+	if len(s.heap) > 0 {
+		d, ok = s.heap[0].next.Sub(now), true
+	}
+	if s.refreshInterval > 0 {
+		if r := s.refreshAt.Sub(now); !ok || r < d {
+			d, ok = r, true
+		}
+	}
+	return d, ok
+}
+
+// runInBackground schedules background checkers and runs them in a pool of workers.
+// It syncs the scheduled checks when checkers change, and refreshes their statuses periodically.
+func (h *HealthCheck) runInBackground(ctx context.Context) {
+	h.mutex.RLock()
+	reload := h.reload
+	workers := h.workers
+	h.mutex.RUnlock()
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	jobs := make(chan *scheduledCheck)
+	done := make(chan *scheduledCheck, workers)
+	for i := 0; i < workers; i++ {
+		go runWorker(ctx, jobs, done)
+	}
+	s := newScheduler()
+	s.sync(h.tagged(nil), time.Now())
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+	for {
+		now := time.Now()
+		s.refresh(now)
+		s.due(now)
+		d, ok := s.wait(now)
+		resetTimer(timer, d, ok)
+		var (
+			dispatch chan *scheduledCheck
+			next     = s.next()
+		)
+		if next != nil {
+			dispatch = jobs
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			s.sync(h.tagged(nil), time.Now())
+		case <-timer.C:
+		case dispatch <- next:
+			s.dispatched()
+		case sc := <-done:
+			s.reschedule(sc, time.Now())
+		}
+	}
+}
+
+// runWorker runs the checks it receives until the context is canceled.
+func runWorker(ctx context.Context, jobs <-chan *scheduledCheck, done chan<- *scheduledCheck) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sc := <-jobs:
+			sc.checker.run(ctx)
+			select {
+			case done <- sc:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// resetTimer stops a timer and resets it to d if ok is true. It works with both buffered and unbuffered timer channels.
+func resetTimer(timer *time.Timer, d time.Duration, ok bool) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	if ok {
+		timer.Reset(d)
+	}
+}
@@ -0,0 +1,324 @@
+package healthcheck
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_scheduleHeap(t *testing.T) {
+	now := time.Now()
+	checks := []*scheduledCheck{
+		{name: "checker_3", next: now.Add(3 * time.Second)},
+		{name: "checker_1", next: now.Add(time.Second)},
+		{name: "checker_4", next: now.Add(4 * time.Second)},
+		{name: "checker_2", next: now.Add(2 * time.Second)},
+	}
+	var h scheduleHeap
+	for i := range checks {
+		heap.Push(&h, checks[i])
+	}
+	for i := range h {
+		if h[i].index != i {
+			t.Errorf("scheduleHeap[%v].index = %v, want %v", i, h[i].index, i)
+		}
+	}
+	want := []string{"checker_1", "checker_2", "checker_3", "checker_4"}
+	var got []string
+	for h.Len() > 0 {
+		c := heap.Pop(&h).(*scheduledCheck)
+		if c.index != -1 {
+			t.Errorf("scheduleHeap.Pop().index = %v, want -1", c.index)
+		}
+		got = append(got, c.name)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scheduleHeap order = %v, want %v", got, want)
+	}
+}
+
+func Test_scheduler_sync(t *testing.T) {
+	now := time.Now()
+	kept := &mockCheck{interval: time.Hour}
+	replaced := &mockCheck{interval: time.Hour}
+	replacement := &mockCheck{interval: time.Hour}
+	removed := &mockCheck{interval: time.Hour}
+	removedPending := &mockCheck{interval: time.Hour}
+	added := &mockCheck{interval: time.Hour}
+	s := newScheduler()
+	s.sync(map[string]checker{
+		"kept":            kept,
+		"replaced":        replaced,
+		"removed":         removed,
+		"removed_pending": removedPending,
+		"front":           &mockCheck{},
+	}, now.Add(-time.Minute))
+	s.checks["removed_pending"].next = now.Add(-2 * time.Minute)
+	heap.Fix(&s.heap, s.checks["removed_pending"].index)
+	s.due(now.Add(-time.Minute - time.Second))
+	pending := s.next()
+	if pending == nil || pending.name != "removed_pending" {
+		t.Fatalf("scheduler.next() = %v, want removed_pending", pending)
+	}
+
+	s.sync(map[string]checker{
+		"kept":     kept,
+		"replaced": replacement,
+		"added":    added,
+		"front":    &mockCheck{},
+	}, now)
+	got := make(map[string]checker)
+	for name, sc := range s.checks {
+		got[name] = sc.checker
+	}
+	want := map[string]checker{"kept": kept, "replaced": replacement, "added": added}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("scheduler.sync() checks = %v, want %v", got, want)
+	}
+	if s.heap.Len() != 3 {
+		t.Errorf("scheduler.sync() len(heap) = %v, want %v", s.heap.Len(), 3)
+	}
+	if !pending.removed {
+		t.Error("scheduler.sync() removed pending check is not marked as removed")
+	}
+	if next := s.next(); next != nil {
+		t.Errorf("scheduler.next() = %v, want nil", next)
+	}
+	if next := s.checks["added"].next; !next.Equal(now) {
+		t.Errorf("scheduler.sync() added next = %v, want %v", next, now)
+	}
+	s.sync(map[string]checker{
+		"delayed": &mockCheck{interval: time.Hour, delay: time.Minute},
+	}, now)
+	if next := s.checks["delayed"].next; !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("scheduler.sync() delayed next = %v, want %v", next, now.Add(time.Minute))
+	}
+	if firstRun := s.checks["delayed"].checker.(*mockCheck).firstRun; !firstRun.Equal(now.Add(time.Minute)) {
+		t.Errorf("scheduler.sync() delayed firstRun = %v, want %v", firstRun, now.Add(time.Minute))
+	}
+}
+
+func Test_scheduler_refresh(t *testing.T) {
+	now := time.Now()
+	s := newScheduler()
+	s.sync(map[string]checker{
+		"fast": newCheck(func(_ context.Context) error { return nil }, time.Second, InBackground(time.Second)),
+		"slow": newCheck(func(_ context.Context) error { return nil }, time.Second, InBackground(time.Hour)),
+	}, now)
+	if s.refreshInterval != time.Second {
+		t.Errorf("scheduler.sync() refreshInterval = %v, want %v", s.refreshInterval, time.Second)
+	}
+	mock := &mockCheck{}
+	s.checks["fast"].checker = mock
+	s.heap = nil
+	if d, ok := s.wait(now); !ok || d != time.Second {
+		t.Errorf("scheduler.wait() = %v, %v, want %v, %v", d, ok, time.Second, true)
+	}
+	s.refresh(now)
+	if mock.refreshes != 0 {
+		t.Errorf("scheduler.refresh() refreshes = %v, want %v", mock.refreshes, 0)
+	}
+	s.refresh(now.Add(time.Second))
+	if mock.refreshes != 1 {
+		t.Errorf("scheduler.refresh() refreshes = %v, want %v", mock.refreshes, 1)
+	}
+	if !s.refreshAt.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("scheduler.refresh() refreshAt = %v, want %v", s.refreshAt, now.Add(2*time.Second))
+	}
+}
+
+func Test_scheduler_reschedule(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		next     time.Time
+		removed  bool
+		want     time.Time
+		wantHeap int
+	}{
+		{
+			"on_time",
+			now,
+			false,
+			now.Add(time.Minute),
+			1,
+		},
+		{
+			"late",
+			now.Add(-time.Hour),
+			false,
+			now,
+			1,
+		},
+		{
+			"removed",
+			now,
+			true,
+			now,
+			0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler()
+			sc := &scheduledCheck{
+				checker: &mockCheck{interval: time.Minute},
+				next:    tt.next,
+				index:   -1,
+				removed: tt.removed,
+			}
+			s.reschedule(sc, now)
+			if !sc.next.Equal(tt.want) {
+				t.Errorf("scheduler.reschedule() next = %v, want %v", sc.next, tt.want)
+			}
+			if s.heap.Len() != tt.wantHeap {
+				t.Errorf("scheduler.reschedule() len(heap) = %v, want %v", s.heap.Len(), tt.wantHeap)
+			}
+		})
+	}
+}
+
+func Test_scheduler_due(t *testing.T) {
+	now := time.Now()
+	s := newScheduler()
+	s.sync(map[string]checker{
+		"checker_1": &mockCheck{interval: time.Hour},
+		"checker_2": &mockCheck{interval: time.Hour},
+	}, now)
+	s.checks["checker_2"].next = now.Add(time.Second)
+	heap.Fix(&s.heap, s.checks["checker_2"].index)
+	if d, ok := s.wait(now); !ok || d != 0 {
+		t.Errorf("scheduler.wait() = %v, %v, want %v, %v", d, ok, 0, true)
+	}
+	s.due(now)
+	if next := s.next(); next == nil || next.name != "checker_1" {
+		t.Errorf("scheduler.next() = %v, want checker_1", next)
+	}
+	s.dispatched()
+	if next := s.next(); next != nil {
+		t.Errorf("scheduler.next() = %v, want nil", next)
+	}
+	if d, ok := s.wait(now); !ok || d != time.Second {
+		t.Errorf("scheduler.wait() = %v, %v, want %v, %v", d, ok, time.Second, true)
+	}
+	s.due(now.Add(time.Second))
+	if d, ok := s.wait(now); ok {
+		t.Errorf("scheduler.wait() = %v, %v, want %v, %v", d, ok, 0, false)
+	}
+}
+
+func TestWithWorkers(t *testing.T) {
+	h := &HealthCheck{}
+	opt := WithWorkers(10)
+	opt(h)
+	if h.workers != 10 {
+		t.Errorf("WithWorkers().workers = %v, want %v", h.workers, 10)
+	}
+}
+
+// Not checking if scheduler is actually working as we expected.
+func TestHealthCheck_runInBackground(t *testing.T) {
+	testErr := errors.New("HealthCheck.runInBackground error")
+	type fields struct {
+		checkers map[string]checker
+	}
+	type args struct {
+		ctx context.Context
+	}
+	tests := []struct {
+		name   string
+		fields fields
+		args   args
+	}{
+		{
+			"empty",
+			fields{},
+			args{context.Background()},
+		},
+		{
+			"2_backgrounds",
+			fields{map[string]checker{
+				"background_1": &mockCheck{interval: time.Millisecond, runErr: testErr},
+				"background_2": &mockCheck{interval: time.Millisecond, runErr: testErr},
+			}},
+			args{context.Background()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &HealthCheck{
+				checkers: tt.fields.checkers,
+			}
+			ctx, cancel := context.WithTimeout(tt.args.ctx, 10*time.Millisecond)
+			defer cancel()
+			h.runInBackground(ctx)
+			for name, c := range tt.fields.checkers {
+				if err := c.state(); err != testErr {
+					t.Errorf("runInBackground() %v checker.err = %v, want %v", name, err, testErr)
+				}
+			}
+		})
+	}
+}
+
+// A slow background check doesn't delay other background checks.
+func TestHealthCheck_runInBackground_independent(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		runs  = make(map[string]int)
+	)
+	checkerCreator := func(name string, sleep time.Duration) Checker {
+		return func(_ context.Context) error {
+			mutex.Lock()
+			runs[name]++
+			mutex.Unlock()
+			time.Sleep(sleep)
+			return nil
+		}
+	}
+	h := &HealthCheck{
+		checkers: map[string]checker{
+			"slow": newCheck(checkerCreator("slow", time.Second), time.Second, InBackground(time.Millisecond)),
+			"fast": newCheck(checkerCreator("fast", 0), time.Second, InBackground(time.Millisecond)),
+		},
+		workers: 2,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	h.runInBackground(ctx)
+	mutex.Lock()
+	defer mutex.Unlock()
+	if runs["slow"] != 1 {
+		t.Errorf("runInBackground() slow runs = %v, want %v", runs["slow"], 1)
+	}
+	if runs["fast"] < 10 {
+		t.Errorf("runInBackground() fast runs = %v, want at least %v", runs["fast"], 10)
+	}
+}
+
+func Test_resetTimer(t *testing.T) {
+	timer := time.NewTimer(time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	resetTimer(timer, time.Hour, true)
+	select {
+	case <-timer.C:
+		t.Error("resetTimer() timer fired, want reset")
+	case <-time.After(5 * time.Millisecond):
+	}
+	resetTimer(timer, time.Nanosecond, true)
+	select {
+	case <-timer.C:
+	case <-time.After(time.Second):
+		t.Error("resetTimer() timer not fired")
+	}
+	resetTimer(timer, 0, false)
+	select {
+	case <-timer.C:
+		t.Error("resetTimer() timer fired, want stopped")
+	case <-time.After(5 * time.Millisecond):
+	}
+}